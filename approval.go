@@ -0,0 +1,210 @@
+package gsd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ChanApprover is an Approver implementation receiving approval decisions
+// from a channel, e.g. fed by a chat bot.
+type ChanApprover <-chan Approval
+
+func (c ChanApprover) RequestApproval(ctx context.Context, _ ApprovalRequest) (Approval, error) {
+	select {
+	case approval, ok := <-c:
+		if !ok {
+			return Approval{}, errors.New("approval channel closed")
+		}
+		return approval, nil
+
+	case <-ctx.Done():
+		return Approval{}, ctx.Err()
+	}
+}
+
+// PromptApprover is an Approver implementation prompting for a decision on
+// an interactive terminal: the approval is granted if the answer is "y" or
+// "yes". Note: the reading of an answer cannot be interrupted, if the
+// context is done before an answer is read, the answer read next is
+// discarded.
+type PromptApprover struct {
+	in   *bufio.Reader
+	out  io.Writer
+	name string
+}
+
+// NewPromptApprover returns a new prompt approver writing the prompt to w and
+// reading the answer from r. The approver identity recorded in the approval
+// decisions is name.
+func NewPromptApprover(r io.Reader, w io.Writer, name string) *PromptApprover {
+	return &PromptApprover{in: bufio.NewReader(r), out: w, name: name}
+}
+
+// NewStdinApprover returns a new prompt approver prompting on the standard
+// error output and reading the answer from the standard input. The approver
+// identity recorded in the approval decisions is the current user's login
+// name, as reported by the USER environment variable.
+func NewStdinApprover() *PromptApprover {
+	return NewPromptApprover(os.Stdin, os.Stderr, os.Getenv("USER"))
+}
+
+func (a *PromptApprover) RequestApproval(ctx context.Context, req ApprovalRequest) (Approval, error) {
+	if _, err := fmt.Fprintf(a.out, "%s: %s [y/N] ", req.Step, req.Message); err != nil {
+		return Approval{}, err
+	}
+
+	answerCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		answer, err := a.in.ReadString('\n')
+		if err != nil && (err != io.EOF || answer == "") {
+			errCh <- err
+			return
+		}
+		answerCh <- answer
+	}()
+
+	select {
+	case answer := <-answerCh:
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return Approval{Approved: true, Approver: a.name}, nil
+		}
+		return Approval{Approved: false, Approver: a.name}, nil
+
+	case err := <-errCh:
+		return Approval{}, fmt.Errorf("unable to read answer: %w", err)
+
+	case <-ctx.Done():
+		return Approval{}, ctx.Err()
+	}
+}
+
+// HTTPApprover is an Approver implementation receiving approval decisions
+// through HTTP callbacks. It implements the http.Handler interface, to be
+// mounted on the HTTP server of the user's choice:
+//
+//   - GET requests return the pending approval request as JSON, or a 404
+//     status if there is none.
+//   - POST requests submit a decision for the pending approval request,
+//     as a JSON-encoded Approval. They return a 409 status if there is no
+//     pending approval request.
+//
+// All requests are authenticated, requests failing authentication are
+// rejected with a 401 status. The approver identity recorded in the approval
+// decisions is the one returned by the authentication function, the
+// "approver" field of the submitted decisions is ignored.
+type HTTPApprover struct {
+	// Notify is an optional function called when an approval is requested,
+	// e.g. to send a message to the approvers with the callback URL.
+	Notify func(context.Context, ApprovalRequest) error
+
+	authenticate func(*http.Request) (string, error)
+
+	mu       sync.Mutex
+	pending  *ApprovalRequest
+	decision chan Approval
+}
+
+// NewHTTPApprover returns a new HTTP approver authenticating the requests
+// with the function authenticate, which returns the identity of the
+// requester or an error if the request is not authenticated (e.g. checking a
+// client certificate or a signed token). A nil function rejects all
+// requests.
+func NewHTTPApprover(authenticate func(*http.Request) (string, error)) *HTTPApprover {
+	return &HTTPApprover{authenticate: authenticate}
+}
+
+func (a *HTTPApprover) RequestApproval(ctx context.Context, req ApprovalRequest) (Approval, error) {
+	decision := make(chan Approval, 1)
+
+	a.mu.Lock()
+	if a.pending != nil {
+		a.mu.Unlock()
+		return Approval{}, errors.New("an approval request is already pending")
+	}
+	a.pending, a.decision = &req, decision
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		a.pending, a.decision = nil, nil
+		a.mu.Unlock()
+	}()
+
+	if a.Notify != nil {
+		if err := a.Notify(ctx, req); err != nil {
+			return Approval{}, fmt.Errorf("unable to notify approvers: %w", err)
+		}
+	}
+
+	select {
+	case approval := <-decision:
+		return approval, nil
+
+	case <-ctx.Done():
+		return Approval{}, ctx.Err()
+	}
+}
+
+func (a *HTTPApprover) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.authenticate == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	approver, err := a.authenticate(r)
+	if err != nil || approver == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.mu.Lock()
+		pending := a.pending
+		a.mu.Unlock()
+
+		if pending == nil {
+			http.Error(w, "no pending approval request", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(pending)
+
+	case http.MethodPost:
+		var approval Approval
+		if err := json.NewDecoder(r.Body).Decode(&approval); err != nil {
+			http.Error(w, fmt.Sprintf("invalid approval: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		approval.Approver = approver
+
+		a.mu.Lock()
+		decision := a.decision
+		a.pending, a.decision = nil, nil
+		a.mu.Unlock()
+
+		if decision == nil {
+			http.Error(w, "no pending approval request", http.StatusConflict)
+			return
+		}
+
+		decision <- approval
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,294 @@
+// Package chaos provides fault injection for gsd plans, allowing to exercise
+// failure handling and cleanup logic systematically.
+//
+// A Monkey injects faults in plan steps through a gsd.Middleware:
+//
+//	monkey := chaos.New(42, chaos.Rule{Phase: gsd.PhaseExec, ErrorProbability: 0.2})
+//	plan, err := gsd.NewPlan(gsd.PlanOptMiddleware(monkey.Middleware()))
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/falzm/gsd"
+)
+
+// ErrInjected represents the error returned by step hooks failed on purpose.
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Fault represents a type of injected fault.
+type Fault int
+
+const (
+	// FaultError makes a step hook return an error (wrapping ErrInjected)
+	// instead of being executed. The Cleanup hook cannot fail, so errors are
+	// never injected in the cleanup phase.
+	FaultError Fault = iota
+
+	// FaultDelay delays the execution of a step hook, or until the step's
+	// context is done.
+	FaultDelay
+
+	// FaultPanic makes a step hook panic (with an error wrapping ErrInjected)
+	// instead of being executed. Note: the gsd plan executor doesn't recover
+	// from panics, so this fault should only be used with an outer middleware
+	// recovering from them, such as the one returned by Recover.
+	FaultPanic
+)
+
+func (f Fault) String() string {
+	switch f {
+	case FaultError:
+		return "error"
+	case FaultDelay:
+		return "delay"
+	case FaultPanic:
+		return "panic"
+	}
+
+	return fmt.Sprintf("Fault(%d)", int(f))
+}
+
+// Rule represents a fault injection rule.
+type Rule struct {
+	// Step is the name of the steps the rule applies to. If empty, the rule
+	// applies to all steps.
+	Step string
+
+	// Phase is the step execution phase the rule applies to. If empty, the
+	// rule applies to all phases.
+	Phase gsd.Phase
+
+	// ErrorProbability is the probability (between 0 and 1) to inject an
+	// error fault.
+	ErrorProbability float64
+
+	// DelayProbability is the probability (between 0 and 1) to inject a
+	// delay fault.
+	DelayProbability float64
+
+	// Delay is the duration of the injected delay faults.
+	Delay time.Duration
+
+	// PanicProbability is the probability (between 0 and 1) to inject a
+	// panic fault.
+	PanicProbability float64
+}
+
+func (r *Rule) matches(step string, phase gsd.Phase) bool {
+	return (r.Step == "" || r.Step == step) && (r.Phase == "" || r.Phase == phase)
+}
+
+// Injection represents a record of an injected fault.
+type Injection struct {
+	// Step is the name of the step the fault has been injected in.
+	Step string
+
+	// Phase is the step execution phase the fault has been injected in.
+	Phase gsd.Phase
+
+	// Fault is the type of the injected fault.
+	Fault Fault
+
+	// Delay is the duration of the injected delay, for FaultDelay faults.
+	Delay time.Duration
+}
+
+// Monkey injects faults in plan steps according to a set of rules.
+type Monkey struct {
+	mu         sync.Mutex
+	rng        *rand.Rand
+	rules      []Rule
+	injections []Injection
+}
+
+// New returns a new Monkey injecting faults according to the rules, drawn
+// from a pseudo-random number generator initialized with seed: a given seed
+// always leads to the same faults being injected for a given plan.
+func New(seed int64, rules ...Rule) *Monkey {
+	return &Monkey{
+		rng:   rand.New(rand.NewSource(seed)), // nolint:gosec
+		rules: rules,
+	}
+}
+
+// Injections returns the faults injected so far, in the order they have been
+// injected.
+func (m *Monkey) Injections() []Injection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	injections := make([]Injection, len(m.injections))
+	copy(injections, m.injections)
+
+	return injections
+}
+
+// Middleware returns a gsd.Middleware injecting faults in the steps it
+// wraps.
+func (m *Monkey) Middleware() gsd.Middleware {
+	return func(next gsd.Step) gsd.Step {
+		return &step{WrappedStep: gsd.WrappedStep{Step: next}, monkey: m}
+	}
+}
+
+// draw returns the faults to inject in the phase of the step named name.
+func (m *Monkey) draw(name string, phase gsd.Phase) []Injection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var injections []Injection
+
+	for i := range m.rules {
+		rule := &m.rules[i]
+		if !rule.matches(name, phase) {
+			continue
+		}
+
+		if rule.DelayProbability > 0 && m.rng.Float64() < rule.DelayProbability {
+			injections = append(injections, Injection{Step: name, Phase: phase, Fault: FaultDelay, Delay: rule.Delay})
+		}
+
+		if rule.PanicProbability > 0 && m.rng.Float64() < rule.PanicProbability {
+			injections = append(injections, Injection{Step: name, Phase: phase, Fault: FaultPanic})
+			break
+		}
+
+		if phase != gsd.PhaseCleanup && rule.ErrorProbability > 0 && m.rng.Float64() < rule.ErrorProbability {
+			injections = append(injections, Injection{Step: name, Phase: phase, Fault: FaultError})
+			break
+		}
+	}
+
+	m.injections = append(m.injections, injections...)
+
+	return injections
+}
+
+// step is a gsd.Step wrapper injecting faults in the wrapped step.
+type step struct {
+	gsd.WrappedStep
+	monkey *Monkey
+}
+
+// inject injects the faults drawn for the phase of the step. It returns a
+// non-nil error if the phase hook must not be executed.
+func (s *step) inject(ctx context.Context, phase gsd.Phase) error {
+	name := gsd.NameOf(s.Step)
+
+	for _, injection := range s.monkey.draw(name, phase) {
+		switch injection.Fault {
+		case FaultDelay:
+			timer := gsd.ContextClock(ctx).NewTimer(injection.Delay)
+			select {
+			case <-timer.C():
+			case <-ctx.Done():
+				timer.Stop()
+			}
+
+		case FaultPanic:
+			panic(fmt.Errorf("%w (panic) in step %q %s phase", ErrInjected, name, phase))
+
+		case FaultError:
+			return fmt.Errorf("%w in step %q %s phase", ErrInjected, name, phase)
+		}
+	}
+
+	return nil
+}
+
+func (s *step) PreExec(ctx context.Context, state *gsd.State) error {
+	if err := s.inject(ctx, gsd.PhasePreExec); err != nil {
+		return err
+	}
+
+	return s.Step.PreExec(ctx, state)
+}
+
+func (s *step) Exec(ctx context.Context, state *gsd.State) error {
+	if err := s.inject(ctx, gsd.PhaseExec); err != nil {
+		return err
+	}
+
+	return s.Step.Exec(ctx, state)
+}
+
+func (s *step) PostExec(ctx context.Context, state *gsd.State) error {
+	if err := s.inject(ctx, gsd.PhasePostExec); err != nil {
+		return err
+	}
+
+	return s.Step.PostExec(ctx, state)
+}
+
+func (s *step) Cleanup(ctx context.Context, state *gsd.State) {
+	_ = s.inject(ctx, gsd.PhaseCleanup)
+
+	s.Step.Cleanup(ctx, state)
+}
+
+// Recover returns a gsd.Middleware recovering from the panics injected in
+// the steps it wraps, turning them into errors wrapping ErrInjected, so that
+// the plan execution fails and enters its cleanup phase. Injected panics are
+// ignored in the cleanup phase, and other panics are not recovered from. It
+// must be applied before (i.e. around) the Monkey middleware:
+//
+//	plan, err := gsd.NewPlan(gsd.PlanOptMiddleware(chaos.Recover(), monkey.Middleware()))
+func Recover() gsd.Middleware {
+	return func(next gsd.Step) gsd.Step {
+		return &recoverStep{WrappedStep: gsd.WrappedStep{Step: next}}
+	}
+}
+
+// recoverStep is a gsd.Step wrapper recovering from the panics injected in
+// the wrapped step.
+type recoverStep struct {
+	gsd.WrappedStep
+}
+
+// recoverInjected recovers from an injected panic, storing it in err (if not
+// nil). Other panics are propagated.
+func recoverInjected(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	if perr, ok := r.(error); ok && errors.Is(perr, ErrInjected) {
+		if err != nil {
+			*err = perr
+		}
+		return
+	}
+
+	panic(r)
+}
+
+func (s *recoverStep) PreExec(ctx context.Context, state *gsd.State) (err error) {
+	defer recoverInjected(&err)
+
+	return s.Step.PreExec(ctx, state)
+}
+
+func (s *recoverStep) Exec(ctx context.Context, state *gsd.State) (err error) {
+	defer recoverInjected(&err)
+
+	return s.Step.Exec(ctx, state)
+}
+
+func (s *recoverStep) PostExec(ctx context.Context, state *gsd.State) (err error) {
+	defer recoverInjected(&err)
+
+	return s.Step.PostExec(ctx, state)
+}
+
+func (s *recoverStep) Cleanup(ctx context.Context, state *gsd.State) {
+	defer recoverInjected(nil)
+
+	s.Step.Cleanup(ctx, state)
+}
@@ -0,0 +1,98 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falzm/gsd"
+	"github.com/falzm/gsd/gsdtest"
+)
+
+func TestMonkey_Error(t *testing.T) {
+	var (
+		rec    = gsdtest.NewRecorder()
+		monkey = New(0, Rule{Step: "b", Phase: gsd.PhaseExec, ErrorProbability: 1})
+	)
+
+	plan, err := gsd.NewPlan(gsd.PlanOptMiddleware(monkey.Middleware()))
+	require.NoError(t, err)
+
+	err = plan.
+		AddStep(gsdtest.NewRecorderStep(rec, "a")).
+		AddStep(gsdtest.NewRecorderStep(rec, "b")).
+		Execute(context.Background())
+	require.True(t, errors.Is(err, ErrInjected))
+
+	gsdtest.AssertPhaseOrder(t, rec,
+		"a:pre-exec", "a:exec", "a:post-exec",
+		"b:pre-exec",
+		"a:cleanup")
+	require.Equal(t, []Injection{{Step: "b", Phase: gsd.PhaseExec, Fault: FaultError}}, monkey.Injections())
+}
+
+func TestMonkey_Delay(t *testing.T) {
+	var (
+		clock  = gsdtest.NewClock(time.Now())
+		monkey = New(0, Rule{Phase: gsd.PhasePreExec, DelayProbability: 1, Delay: time.Hour})
+	)
+
+	plan, err := gsd.NewPlan(gsd.PlanOptClock(clock), gsd.PlanOptMiddleware(monkey.Middleware()))
+	require.NoError(t, err)
+
+	go func() {
+		clock.WaitTimers(1)
+		clock.Advance(time.Hour)
+	}()
+
+	require.NoError(t, plan.
+		AddStep(&gsd.GenericStep{Name: "a"}).
+		Execute(context.Background()))
+	require.Equal(t, []Injection{{Step: "a", Phase: gsd.PhasePreExec, Fault: FaultDelay, Delay: time.Hour}},
+		monkey.Injections())
+}
+
+func TestMonkey_Panic(t *testing.T) {
+	var (
+		rec    = gsdtest.NewRecorder()
+		monkey = New(0, Rule{Step: "b", Phase: gsd.PhaseExec, PanicProbability: 1})
+	)
+
+	plan, err := gsd.NewPlan(gsd.PlanOptMiddleware(Recover(), monkey.Middleware()))
+	require.NoError(t, err)
+
+	err = plan.
+		AddStep(gsdtest.NewRecorderStep(rec, "a")).
+		AddStep(gsdtest.NewRecorderStep(rec, "b")).
+		Execute(context.Background())
+	require.True(t, errors.Is(err, ErrInjected))
+	require.EqualError(t, err, `chaos: injected failure (panic) in step "b" exec phase`)
+
+	gsdtest.AssertPhaseOrder(t, rec,
+		"a:pre-exec", "a:exec", "a:post-exec",
+		"b:pre-exec",
+		"a:cleanup")
+}
+
+func TestMonkey_Seed(t *testing.T) {
+	run := func(seed int64) []Injection {
+		monkey := New(seed, Rule{ErrorProbability: 0.3})
+
+		plan, err := gsd.NewPlan(gsd.PlanOptContinueOnError(), gsd.PlanOptMiddleware(monkey.Middleware()))
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			plan.AddStep(&gsd.GenericStep{Name: fmt.Sprintf("step-%d", i)})
+		}
+		_ = plan.Execute(context.Background())
+
+		return monkey.Injections()
+	}
+
+	require.NotEmpty(t, run(1))
+	require.Equal(t, run(1), run(1))
+}
@@ -0,0 +1,242 @@
+package gsd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrCheckpointMismatch represents an error reported when resuming a plan
+// from a checkpoint recorded by a different plan.
+var ErrCheckpointMismatch = errors.New("checkpoint doesn't match plan")
+
+// CheckpointStep represents the identity of a step recorded in a
+// checkpoint.
+type CheckpointStep struct {
+	// Index is the index (starting at 0) of the step in the plan.
+	Index int `json:"index"`
+
+	// Name is the name of the step, if it implements the Named interface.
+	Name string `json:"name,omitempty"`
+}
+
+// Checkpoint represents the progress of a plan execution, recorded after
+// each completed step.
+type Checkpoint struct {
+	// Completed are the steps completed, in execution order. Steps not
+	// selected for execution (see PlanOptRunFrom) are not listed.
+	Completed []CheckpointStep
+
+	// Next is the index (starting at 0) of the next step to execute: the
+	// steps preceding it are not executed when resuming.
+	Next int
+
+	// State is a snapshot of the plan state after the last completed step.
+	State *StateSnapshot
+}
+
+// CheckpointStore represents a durable storage for plan execution
+// checkpoints.
+type CheckpointStore interface {
+	// Save saves the checkpoint c, replacing the previous one (if any).
+	Save(ctx context.Context, c *Checkpoint) error
+
+	// Load returns the latest checkpoint saved, or nil if there is none.
+	Load(ctx context.Context) (*Checkpoint, error)
+
+	// Clear deletes the latest checkpoint saved (if any).
+	Clear(ctx context.Context) error
+}
+
+// PlanOptCheckpointStore instructs the plan to record a checkpoint in the
+// store s after each completed step, allowing to resume an interrupted
+// execution using the Plan.Resume method. The checkpoint is cleared once the
+// plan execution is over, i.e. after the cleanup phase.
+func PlanOptCheckpointStore(s CheckpointStore) PlanOpt {
+	return func(p *Plan) error {
+		p.checkpointStore = s
+		return nil
+	}
+}
+
+// Resume resumes the execution of the plan from the latest checkpoint
+// recorded in the store s: the steps completed are skipped, and the plan
+// state is restored from the checkpoint before executing the remaining steps,
+// recording new checkpoints in s. The completed steps are still cleaned up
+// during the cleanup phase, unlike the steps skipped by the steps selection
+// options. If there is no checkpoint in the store, the plan
+// is executed from the beginning. It returns an error wrapping
+// ErrCheckpointMismatch if the checkpoint doesn't match the plan's steps.
+func (p *Plan) Resume(ctx context.Context, s CheckpointStore) error {
+	checkpoint, err := s.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load checkpoint: %w", err)
+	}
+
+	if checkpoint != nil {
+		if err := p.checkCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+
+	return p.execute(ctx, s, checkpoint, nil)
+}
+
+// checkCheckpoint checks that the checkpoint c has been recorded by the plan.
+func (p *Plan) checkCheckpoint(c *Checkpoint) error {
+	if c.Next > p.steps.Len() {
+		return fmt.Errorf("%w: next step %d, plan has %d steps",
+			ErrCheckpointMismatch, c.Next+1, p.steps.Len())
+	}
+
+	steps := make([]Step, 0, p.steps.Len())
+	for s := p.steps.Front(); s != nil; s = s.Next() {
+		steps = append(steps, s.Value.(Step))
+	}
+
+	prev := -1
+	for _, completed := range c.Completed {
+		if completed.Index <= prev || completed.Index >= c.Next {
+			return fmt.Errorf("%w: unexpected completed step %d", ErrCheckpointMismatch, completed.Index+1)
+		}
+		prev = completed.Index
+
+		if name := NameOf(steps[completed.Index]); completed.Name != name {
+			return fmt.Errorf("%w: completed step %d %q, plan step %d is %q",
+				ErrCheckpointMismatch, completed.Index+1, completed.Name, completed.Index+1, name)
+		}
+	}
+
+	return nil
+}
+
+// saveCheckpoint records a checkpoint in the store s, before the execution of
+// the step at index next (starting at 0). The steps completed are the ones
+// flagged in executed.
+func (p *Plan) saveCheckpoint(ctx context.Context, s CheckpointStore, steps []Step, executed []bool, next int) error {
+	c := Checkpoint{
+		Next:  next,
+		State: p.state.Snapshot(),
+	}
+
+	for i := 0; i < next; i++ {
+		if executed[i] {
+			c.Completed = append(c.Completed, CheckpointStep{Index: i, Name: NameOf(steps[i])})
+		}
+	}
+
+	if err := s.Save(ctx, &c); err != nil {
+		return fmt.Errorf("unable to save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// FileCheckpointStore is a CheckpointStore implementation storing
+// checkpoints as a JSON file.
+type FileCheckpointStore struct {
+	path     string
+	registry *TypeRegistry
+}
+
+// NewFileCheckpointStore returns a new file checkpoint store storing
+// checkpoints in the file at path, using the type registry reg (or
+// DefaultTypeRegistry if nil) to serialize the plan state. Saving a
+// checkpoint fails if the plan state contains unserializable values.
+func NewFileCheckpointStore(path string, reg *TypeRegistry) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path, registry: reg}
+}
+
+// fileCheckpoint represents the content of a checkpoint file.
+type fileCheckpoint struct {
+	Version   int              `json:"version"`
+	Completed []CheckpointStep `json:"completed"`
+	Next      int              `json:"next"`
+	State     json.RawMessage  `json:"state"`
+}
+
+// fileCheckpointVersion is the version of the checkpoint file format.
+const fileCheckpointVersion = 1
+
+// Save saves the checkpoint c to the store's file. The file is replaced
+// atomically, so that a crash during the save doesn't corrupt the previous
+// checkpoint.
+func (s *FileCheckpointStore) Save(_ context.Context, c *Checkpoint) error {
+	var state bytes.Buffer
+	if err := c.State.EncodeJSON(&state, s.registry); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fileCheckpoint{
+		Version:   fileCheckpointVersion,
+		Completed: c.Completed,
+		Next:      c.Next,
+		State:     state.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // nolint:errcheck
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), s.path)
+}
+
+// Load loads the checkpoint from the store's file, or returns nil if the
+// file doesn't exist.
+func (s *FileCheckpointStore) Load(_ context.Context) (*Checkpoint, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var fc fileCheckpoint
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file: %w", err)
+	}
+
+	if fc.Version != fileCheckpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint file version %d", fc.Version)
+	}
+
+	state, err := DecodeStateSnapshotJSON(bytes.NewReader(fc.State), s.registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checkpoint{Completed: fc.Completed, Next: fc.Next, State: state}, nil
+}
+
+// Clear deletes the store's file.
+func (s *FileCheckpointStore) Clear(_ context.Context) error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testCheckpointPlan(t *testing.T, opts ...PlanOpt) (*Plan, context.Context, context.CancelFunc) {
+	plan, err := NewPlan(opts...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	testStep := func(name string, exec func(context.Context, *State) error) Step {
+		return &GenericStep{
+			Name:        name,
+			ExecFunc:    exec,
+			CleanupFunc: func(ctx context.Context, state *State) { _ = testStepFunc(state, "~"+name) },
+		}
+	}
+
+	return plan.
+			AddStep(testStep("a", func(ctx context.Context, state *State) error { return testStepFunc(state, "a") })).
+			AddStep(testStep("b", func(ctx context.Context, state *State) error {
+				if crash, _ := state.Get("crash").(bool); crash {
+					// Simulate a process crash: the cleanup phase is not executed.
+					cancel()
+					return errors.New("crash")
+				}
+				return testStepFunc(state, "b")
+			})).
+			AddStep(testStep("c", func(ctx context.Context, state *State) error { return errors.New("blah") })),
+		ctx, cancel
+}
+
+func TestPlan_Resume(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"), nil)
+
+	plan, ctx, cancel := testCheckpointPlan(t, PlanOptCheckpointStore(store))
+	defer cancel()
+
+	plan.State().Store("crash", true)
+	require.Equal(t, ErrCancelled, plan.Execute(ctx))
+
+	checkpoint, err := store.Load(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	require.Equal(t, []CheckpointStep{{Index: 0, Name: "a"}}, checkpoint.Completed)
+	require.Equal(t, 1, checkpoint.Next)
+	require.Equal(t, 2, checkpoint.State.Len())
+
+	// Resume the execution in a new plan, after fixing the crash cause.
+	plan, ctx, cancel = testCheckpointPlan(t)
+	defer cancel()
+
+	checkpoint.State.values["crash"] = false
+	require.NoError(t, store.Save(context.Background(), checkpoint))
+
+	require.EqualError(t, plan.Resume(ctx, store), "blah")
+
+	actual, _ := plan.State().Load("test")
+	require.Equal(t, "ab~b~a", actual)
+	require.Equal(t, StepSkipped, plan.Results()[0].Status)
+
+	checkpoint, err = store.Load(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, checkpoint)
+}
+
+func TestPlan_Resume_ContinueOnError(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"), nil)
+
+	plan, err := NewPlan(PlanOptCheckpointStore(store), PlanOptContinueOnError())
+	require.NoError(t, err)
+
+	plan.AddStep(&GenericStep{
+		PostExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+	})
+
+	// Saving the checkpoint after the failed last step must not clear its error.
+	require.EqualError(t, plan.Execute(context.Background()), "blah")
+}
+
+func TestPlan_Resume_StepsSelection(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"), nil)
+
+	newPlan := func(opts ...PlanOpt) (*Plan, context.Context, context.CancelFunc) {
+		plan, err := NewPlan(opts...)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		for _, name := range []string{"a", "b", "c"} {
+			name := name
+			plan.AddStep(&GenericStep{
+				Name: name,
+				ExecFunc: func(ctx context.Context, state *State) error {
+					if crash, _ := state.Get("crash").(bool); crash && name == "c" {
+						cancel()
+						return errors.New("crash")
+					}
+					return testStepFunc(state, name)
+				},
+				CleanupFunc: func(ctx context.Context, state *State) { _ = testStepFunc(state, "~"+name) },
+			})
+		}
+
+		return plan, ctx, cancel
+	}
+
+	plan, ctx, cancel := newPlan(PlanOptCheckpointStore(store), PlanOptOnlySteps("b", "c"))
+	defer cancel()
+
+	plan.State().Store("crash", true)
+	require.Equal(t, ErrCancelled, plan.Execute(ctx))
+
+	checkpoint, err := store.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []CheckpointStep{{Index: 1, Name: "b"}}, checkpoint.Completed)
+	require.Equal(t, 2, checkpoint.Next)
+
+	plan, ctx, cancel = newPlan()
+	defer cancel()
+
+	checkpoint.State.values["crash"] = false
+	require.NoError(t, store.Save(context.Background(), checkpoint))
+	require.NoError(t, plan.Resume(ctx, store))
+
+	// Step "a" has been skipped by the steps selection, it must not be cleaned up.
+	require.Equal(t, "bc~c~b", plan.State().Get("test"))
+}
+
+func TestPlan_Resume_Mismatch(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"), nil)
+	require.NoError(t, store.Save(context.Background(), &Checkpoint{
+		Completed: []CheckpointStep{{Index: 0, Name: "x"}},
+		Next:      1,
+		State:     (&State{}).Snapshot(),
+	}))
+
+	plan, ctx, cancel := testCheckpointPlan(t)
+	defer cancel()
+
+	err := plan.Resume(ctx, store)
+	require.True(t, errors.Is(err, ErrCheckpointMismatch))
+}
+
+func TestPlan_Resume_NoCheckpoint(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"), nil)
+
+	plan, ctx, cancel := testCheckpointPlan(t)
+	defer cancel()
+
+	require.EqualError(t, plan.Resume(ctx, store), "blah")
+
+	actual, _ := plan.State().Load("test")
+	require.Equal(t, "ab~b~a", actual)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"), nil)
+
+	checkpoint, err := store.Load(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, checkpoint)
+	require.NoError(t, store.Clear(context.Background()))
+
+	state := &State{}
+	state.Store("a", "b")
+	expected := &Checkpoint{Completed: []CheckpointStep{{Index: 0, Name: "a"}}, Next: 1, State: state.Snapshot()}
+
+	require.NoError(t, store.Save(context.Background(), expected))
+	checkpoint, err = store.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, expected, checkpoint)
+
+	state.Store("nope", struct{}{})
+	require.Error(t, store.Save(context.Background(), &Checkpoint{State: state.Snapshot()}))
+
+	require.NoError(t, store.Clear(context.Background()))
+	checkpoint, err = store.Load(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, checkpoint)
+}
@@ -0,0 +1,161 @@
+package gsd
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock represents the source of time used by a plan execution for
+// timestamps, timeouts and pauses.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer returns a new Timer sending the current time on its channel
+	// after at least the duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer represents a single event timer, similar to a time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It returns false if the timer
+	// has already expired or been stopped.
+	Stop() bool
+}
+
+// PlanOptClock instructs the plan to use the clock c instead of the system's
+// wall clock for timestamps, timeouts and pauses. It is mostly useful in
+// tests, e.g. combined with the gsdtest package's Clock.
+func PlanOptClock(c Clock) PlanOpt {
+	return func(p *Plan) error {
+		p.clock = c
+		return nil
+	}
+}
+
+// realClock is a Clock implementation based on the system's wall clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type clockCtxKey struct{}
+
+// ContextClock returns the clock of the plan executing the step the context
+// ctx has been passed to, or a Clock based on the system's wall clock if
+// the context doesn't originate from a plan execution. Steps performing
+// time-related operations should use this clock so that they can be tested
+// deterministically.
+func ContextClock(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockCtxKey{}).(Clock); ok {
+		return c
+	}
+
+	return realClock{}
+}
+
+// getClock returns the clock used by the plan.
+func (p *Plan) getClock() Clock {
+	if p.clock == nil {
+		return realClock{}
+	}
+
+	return p.clock
+}
+
+// timeoutCtx is a context.Context implementation expiring according to a
+// Clock, similar to a context returned by context.WithTimeout.
+type timeoutCtx struct {
+	parent   context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// withClockTimeout returns a copy of the parent context which is cancelled
+// when the duration d has elapsed according to the clock c, or when the
+// returned cancel function is called, or when the parent context is done,
+// whichever happens first.
+func withClockTimeout(parent context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx := timeoutCtx{
+		parent:   parent,
+		deadline: c.Now().Add(d),
+		done:     make(chan struct{}),
+	}
+
+	if deadline, ok := parent.Deadline(); ok && deadline.Before(ctx.deadline) {
+		ctx.deadline = deadline
+	}
+
+	timer := c.NewTimer(d)
+	stop := make(chan struct{})
+
+	go func() {
+		defer timer.Stop()
+
+		select {
+		case <-timer.C():
+			ctx.cancel(context.DeadlineExceeded)
+
+		case <-parent.Done():
+			ctx.cancel(parent.Err())
+
+		case <-stop:
+		}
+	}()
+
+	var once sync.Once
+
+	return &ctx, func() {
+		once.Do(func() { close(stop) })
+		ctx.cancel(context.Canceled)
+	}
+}
+
+func (c *timeoutCtx) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+func (c *timeoutCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *timeoutCtx) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *timeoutCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *timeoutCtx) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
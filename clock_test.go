@@ -0,0 +1,54 @@
+package gsd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanOptClock(t *testing.T) {
+	plan := &Plan{}
+	require.Equal(t, realClock{}, plan.getClock())
+
+	clock := &realClock{}
+	require.NoError(t, PlanOptClock(clock)(plan))
+	require.Equal(t, clock, plan.clock)
+	require.Equal(t, clock, plan.getClock())
+}
+
+func TestContextClock(t *testing.T) {
+	require.Equal(t, realClock{}, ContextClock(context.Background()))
+
+	clock := &realClock{}
+	require.Equal(t, clock, ContextClock(context.WithValue(context.Background(), clockCtxKey{}, clock)))
+}
+
+func TestWithClockTimeout(t *testing.T) {
+	ctx, cancel := withClockTimeout(context.Background(), realClock{}, 10*time.Millisecond)
+	defer cancel()
+
+	_, ok := ctx.Deadline()
+	require.True(t, ok)
+	<-ctx.Done()
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+
+	// Derived contexts must report the same error.
+	child, childCancel := context.WithCancel(ctx)
+	defer childCancel()
+	<-child.Done()
+	require.Equal(t, context.DeadlineExceeded, child.Err())
+
+	ctx, cancel = withClockTimeout(context.Background(), realClock{}, time.Hour)
+	cancel()
+	<-ctx.Done()
+	require.Equal(t, context.Canceled, ctx.Err())
+
+	parent, parentCancel := context.WithCancel(context.Background())
+	ctx, cancel = withClockTimeout(parent, realClock{}, time.Hour)
+	defer cancel()
+	parentCancel()
+	<-ctx.Done()
+	require.Equal(t, context.Canceled, ctx.Err())
+}
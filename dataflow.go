@@ -0,0 +1,78 @@
+package gsd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataFlow is an optional interface a Step can implement to declare the
+// State keys it reads and writes. The declared keys are used by the plan
+// validation (see Plan.Validate) to check that every key read by a step is
+// written by a previous step, and enforced during execution: writes to
+// undeclared keys from the step's PreExec, Exec and PostExec hooks are
+// denied, and fail the step with an *UndeclaredWriteError error.
+type DataFlow interface {
+	// Reads returns the State keys the step reads.
+	Reads() []string
+
+	// Writes returns the State keys the step writes (or deletes).
+	Writes() []string
+}
+
+// UndeclaredWriteError represents an error reported when a step implementing
+// the DataFlow interface attempted to write State keys it didn't declare.
+type UndeclaredWriteError struct {
+	// Step is the name of the step, if it implements the Named interface.
+	Step string
+
+	// Keys are the undeclared keys the step attempted to write.
+	Keys []string
+}
+
+func (e *UndeclaredWriteError) Error() string {
+	step := "step"
+	if e.Step != "" {
+		step = fmt.Sprintf("step %q", e.Step)
+	}
+
+	return fmt.Sprintf("%s attempted to write undeclared state keys: %s", step, strings.Join(e.Keys, ", "))
+}
+
+// DeclareKeys returns a middleware declaring the State keys read and written
+// by the step it wraps (see DataFlow), e.g.:
+//
+//	plan.AddStep(step.With(gsd.DeclareKeys([]string{"host"}, []string{"token"})))
+func DeclareKeys(reads, writes []string) Middleware {
+	return func(next Step) Step {
+		return &dataFlowStep{WrappedStep: WrappedStep{Step: next}, reads: reads, writes: writes}
+	}
+}
+
+// dataFlowStep is a Step wrapper implementing the DataFlow interface.
+type dataFlowStep struct {
+	WrappedStep
+	reads  []string
+	writes []string
+}
+
+func (s *dataFlowStep) Reads() []string {
+	return s.reads
+}
+
+func (s *dataFlowStep) Writes() []string {
+	return s.writes
+}
+
+// dataFlowOf returns the DataFlow implementation of the step s, or nil if
+// neither s nor the steps it wraps implement it.
+func dataFlowOf(s Step) DataFlow {
+	var df DataFlow
+
+	unwrapStep(s, func(s Step) bool {
+		var ok bool
+		df, ok = s.(DataFlow)
+		return ok
+	})
+
+	return df
+}
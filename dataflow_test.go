@@ -0,0 +1,79 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeclareKeys(t *testing.T) {
+	step := (&GenericStep{}).With(DeclareKeys([]string{"a"}, []string{"b"}))
+
+	df := dataFlowOf(step)
+	require.NotNil(t, df)
+	require.Equal(t, []string{"a"}, df.Reads())
+	require.Equal(t, []string{"b"}, df.Writes())
+
+	require.Nil(t, dataFlowOf(&GenericStep{}))
+}
+
+func TestPlan_Validate_WithDataFlow(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	plan.State().Store("init", true)
+
+	err = plan.
+		AddStep((&GenericStep{}).With(DeclareKeys([]string{"init"}, []string{"a"}))).
+		AddPause(time.Millisecond).
+		AddStep((&GenericStep{}).With(DeclareKeys([]string{"a", "typo"}, nil))).
+		AddStep(&GenericStep{}).
+		AddStep((&GenericStep{}).With(DeclareKeys([]string{"whatever"}, nil))).
+		Validate(context.Background())
+	require.EqualError(t, err, `plan validation failed: step 3 reads state key "typo" never written before`)
+}
+
+func TestPlan_Execute_WithDataFlow(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	require.NoError(t, plan.
+		AddStep((&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error {
+				state.Store("a", 1)
+				state.Delete("b")
+				return nil
+			},
+			CleanupFunc: func(ctx context.Context, state *State) { state.Store("c", 1) },
+		}).With(DeclareKeys(nil, []string{"a", "b"}))).
+		Execute(context.Background()))
+
+	require.Equal(t, 1, plan.State().Get("a"))
+	require.Equal(t, 1, plan.State().Get("c"))
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	err = plan.
+		AddStep((&GenericStep{
+			Name: "test",
+			ExecFunc: func(ctx context.Context, state *State) error {
+				state.Store("a", 1)
+				state.Store("b", 1)
+				state.LoadOrStore("c", 1)
+				return nil
+			},
+		}).With(DeclareKeys(nil, []string{"a"}))).
+		Execute(context.Background())
+
+	var uerr *UndeclaredWriteError
+	require.True(t, errors.As(err, &uerr))
+	require.Equal(t, []string{"b", "c"}, uerr.Keys)
+	require.EqualError(t, err, `step "test" attempted to write undeclared state keys: b, c`)
+	require.Equal(t, 1, plan.State().Get("a"))
+	require.Nil(t, plan.State().Get("b"))
+	require.Nil(t, plan.State().Get("c"))
+}
@@ -0,0 +1,74 @@
+package gsd
+
+import (
+	"context"
+	"fmt"
+)
+
+// PlanOptDryRun instructs the plan to perform a dry run when executed: the
+// steps hooks are not executed, instead the plan collects the description of
+// the actions each step would perform from the steps implementing the
+// DryRunner interface. The resulting report is returned by the
+// Plan.DryRunReport method.
+func PlanOptDryRun() PlanOpt {
+	return func(p *Plan) error {
+		p.dryRun = true
+		return nil
+	}
+}
+
+// DryRunner is an optional interface a Step can implement to describe the
+// actions it would perform if executed, without side effects.
+type DryRunner interface {
+	// DryRun returns a human-readable description of the actions the step
+	// would perform if executed with the plan state provided.
+	DryRun(context.Context, *State) (string, error)
+}
+
+// DryRunAction represents the intended action of a plan step reported by a
+// dry run.
+type DryRunAction struct {
+	// Step is the index (starting at 0) of the step in the plan.
+	Step int
+
+	// Name is the name of the step, if it implements the Named interface.
+	Name string
+
+	// Description is the description of the actions the step would perform,
+	// empty if the step doesn't implement the DryRunner interface.
+	Description string
+}
+
+// DryRunReport returns the intended actions reported by the latest dry run of
+// the plan (see PlanOptDryRun), in execution order.
+func (p *Plan) DryRunReport() []DryRunAction {
+	return p.dryRunReport
+}
+
+// execDryRun performs a dry run of the plan.
+func (p *Plan) execDryRun(ctx context.Context) error {
+	p.dryRunReport = make([]DryRunAction, 0, p.steps.Len())
+
+	i := 0
+	for s := p.steps.Front(); s != nil; s, i = s.Next(), i+1 {
+		step := s.Value.(Step)
+
+		action := DryRunAction{Step: i, Name: NameOf(step)}
+
+		var err error
+		unwrapStep(step, func(s Step) bool {
+			dr, ok := s.(DryRunner)
+			if ok {
+				action.Description, err = dr.DryRun(ctx, p.state)
+			}
+			return ok
+		})
+		if err != nil {
+			return fmt.Errorf("step %d dry run failed: %w", i+1, err)
+		}
+
+		p.dryRunReport = append(p.dryRunReport, action)
+	}
+
+	return nil
+}
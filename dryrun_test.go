@@ -0,0 +1,62 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testStep struct{}
+
+func (s *testStep) PreExec(_ context.Context, _ *State) error  { return errors.New("blah") }
+func (s *testStep) Exec(_ context.Context, _ *State) error     { return errors.New("blah") }
+func (s *testStep) PostExec(_ context.Context, _ *State) error { return errors.New("blah") }
+func (s *testStep) Cleanup(_ context.Context, _ *State)        {}
+func (s *testStep) Retries() int                               { return 0 }
+
+func TestPlanOptDryRun(t *testing.T) {
+	plan := &Plan{}
+
+	require.NoError(t, PlanOptDryRun()(plan))
+	require.True(t, plan.dryRun)
+}
+
+func TestPlan_Execute_WithDryRun(t *testing.T) {
+	plan, err := NewPlan(PlanOptDryRun())
+	require.NoError(t, err)
+
+	plan.State().Store("who", "world")
+
+	require.NoError(t, plan.
+		AddStep(&GenericStep{
+			Name:     "hello",
+			ExecFunc: func(ctx context.Context, state *State) error { return testStepFunc(state, "nope") },
+			DryRunFunc: func(ctx context.Context, state *State) (string, error) {
+				return "say hello to " + state.Get("who").(string), nil
+			},
+		}).
+		AddPause(time.Hour).
+		AddStep(&testStep{}).
+		Execute(context.Background()))
+
+	_, ok := plan.State().Load("test")
+	require.False(t, ok)
+
+	require.Equal(t, []DryRunAction{
+		{Step: 0, Name: "hello", Description: "say hello to world"},
+		{Step: 1, Name: "pause 1h0m0s", Description: "pause for 1h0m0s"},
+		{Step: 2},
+	}, plan.DryRunReport())
+
+	plan, err = NewPlan(PlanOptDryRun())
+	require.NoError(t, err)
+
+	require.EqualError(t, plan.
+		AddStep(&GenericStep{
+			DryRunFunc: func(ctx context.Context, state *State) (string, error) { return "", errors.New("blah") },
+		}).
+		Execute(context.Background()), "step 1 dry run failed: blah")
+}
@@ -7,3 +7,11 @@ var ErrCancelled = errors.New("plan execution cancelled")
 
 // ErrTimeout represents an error reported if a plan took too long to execute.
 var ErrTimeout = errors.New("plan execution duration limit exceeded")
+
+// ErrWaitTimeout represents an error reported if a condition waited for
+// didn't hold before the wait timeout.
+var ErrWaitTimeout = errors.New("condition wait timeout exceeded")
+
+// ErrAborted represents an error reported if a plan execution has been
+// aborted using the Run.Abort method.
+var ErrAborted = errors.New("plan execution aborted")
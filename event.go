@@ -0,0 +1,212 @@
+package gsd
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EventType represents the type of a plan execution event.
+type EventType int
+
+const (
+	// EventPlanStart is emitted when the plan execution starts.
+	EventPlanStart EventType = iota
+
+	// EventPlanEnd is emitted when the plan execution ends, with the
+	// plan execution error (if any).
+	EventPlanEnd
+
+	// EventStepStart is emitted before the first attempt of a step.
+	EventStepStart
+
+	// EventStepEnd is emitted after the last attempt of a step, with the
+	// step execution error (if any).
+	EventStepEnd
+
+	// EventStepRetry is emitted when a failed step is about to be retried,
+	// with the error of the failed attempt.
+	EventStepRetry
+
+	// EventPhaseStart is emitted before the execution of a step's hook.
+	EventPhaseStart
+
+	// EventPhaseEnd is emitted after the execution of a step's hook, with
+	// the error returned by the hook (if any).
+	EventPhaseEnd
+
+	// EventStepSkip is emitted instead of EventStepStart/EventStepEnd when a
+	// step is not executed.
+	EventStepSkip
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPlanStart:
+		return "plan-start"
+	case EventPlanEnd:
+		return "plan-end"
+	case EventStepStart:
+		return "step-start"
+	case EventStepEnd:
+		return "step-end"
+	case EventStepRetry:
+		return "step-retry"
+	case EventPhaseStart:
+		return "phase-start"
+	case EventPhaseEnd:
+		return "phase-end"
+	case EventStepSkip:
+		return "step-skip"
+	}
+
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Phase represents a phase of a step execution, i.e. one of the Step
+// interface hooks.
+type Phase string
+
+const (
+	PhasePreExec  Phase = "pre-exec"
+	PhaseExec     Phase = "exec"
+	PhasePostExec Phase = "post-exec"
+	PhaseCleanup  Phase = "cleanup"
+)
+
+// Event represents a plan execution event.
+type Event struct {
+	// Type is the type of the event.
+	Type EventType
+
+	// Time is the time at which the event occurred.
+	Time time.Time
+
+	// Total is the total number of steps in the plan.
+	Total int
+
+	// Step is the index (starting at 0) of the step the event relates to,
+	// or -1 for plan-level events.
+	Step int
+
+	// StepName is the name of the step the event relates to, if the step
+	// implements the Named interface.
+	StepName string
+
+	// Phase is the step execution phase the event relates to, only set for
+	// EventPhaseStart and EventPhaseEnd events.
+	Phase Phase
+
+	// Attempt is the attempt number (starting at 1) of the step execution
+	// the event relates to.
+	Attempt int
+
+	// Err is the error reported by the plan, step or phase (if any).
+	Err error
+}
+
+// EventHandler represents a function receiving plan execution events.
+type EventHandler func(Event)
+
+// PlanOptEventHandler registers the function h to receive the plan
+// execution events. This option can be specified multiple times to register
+// several handlers, which are called synchronously by the plan executor in
+// the order they have been registered: they should return quickly.
+func PlanOptEventHandler(h EventHandler) PlanOpt {
+	return func(p *Plan) error {
+		p.eventHandlers = append(p.eventHandlers, h)
+		return nil
+	}
+}
+
+// Named is an optional interface a Step can implement to provide a
+// human-readable name, used to identify the step in execution events.
+type Named interface {
+	StepName() string
+}
+
+// NameOf returns the name of the step s, or an empty string if neither s nor
+// the steps it wraps (see Wrapper) implement the Named interface.
+func NameOf(s Step) string {
+	var name string
+
+	unwrapStep(s, func(s Step) bool {
+		n, ok := s.(Named)
+		if ok {
+			name = n.StepName()
+		}
+		return ok
+	})
+
+	return name
+}
+
+// emit records the event e in the plan's step results and sends it to the
+// plan's event handlers.
+func (p *Plan) emit(e Event) {
+	e.Time = p.getClock().Now()
+	e.Total = p.steps.Len()
+
+	p.recordResult(e)
+
+	for _, h := range p.eventHandlers {
+		h(e)
+	}
+}
+
+// emitPlan sends a plan-level event of type t to the plan's event handlers.
+func (p *Plan) emitPlan(t EventType, err error) {
+	p.emit(Event{Type: t, Step: -1, Err: err})
+}
+
+// execPhase executes the function f as the phase of the step at index i
+// (starting at 0) for the specified attempt (starting at 0), surrounded by
+// the corresponding execution events.
+func (p *Plan) execPhase(
+	ctx context.Context,
+	i int,
+	step Step,
+	attempt int,
+	phase Phase,
+	f func(context.Context, *State) error,
+) error {
+	e := Event{
+		Type:     EventPhaseStart,
+		Step:     i,
+		StepName: NameOf(step),
+		Phase:    phase,
+		Attempt:  attempt + 1,
+	}
+	p.emit(e)
+
+	// Enforce the State keys writes declared by the step (if any).
+	df := dataFlowOf(step)
+	if df != nil && phase != PhaseCleanup {
+		p.state.restrictWrites(df.Writes())
+	}
+
+	err := f(ctx, p.state)
+
+	if df != nil && phase != PhaseCleanup {
+		if keys := p.state.unrestrictWrites(); len(keys) > 0 && err == nil {
+			err = &UndeclaredWriteError{Step: NameOf(step), Keys: keys}
+		}
+	}
+
+	e.Type, e.Err = EventPhaseEnd, err
+	p.emit(e)
+
+	return err
+}
+
+// emitStepEnd sends an EventStepEnd event for the step at index i (starting
+// at 0) to the plan's event handlers.
+func (p *Plan) emitStepEnd(i int, step Step, attempt int, err error) {
+	p.emit(Event{Type: EventStepEnd, Step: i, StepName: NameOf(step), Attempt: attempt + 1, Err: err})
+}
+
+// emitStepRetry sends an EventStepRetry event for the step at index i
+// (starting at 0) to the plan's event handlers.
+func (p *Plan) emitStepRetry(i int, step Step, attempt int, err error) {
+	p.emit(Event{Type: EventStepRetry, Step: i, StepName: NameOf(step), Attempt: attempt + 1, Err: err})
+}
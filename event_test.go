@@ -0,0 +1,80 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanOptEventHandler(t *testing.T) {
+	plan := &Plan{}
+	h := func(Event) {}
+
+	require.NoError(t, PlanOptEventHandler(h)(plan))
+	require.NoError(t, PlanOptEventHandler(h)(plan))
+	require.Len(t, plan.eventHandlers, 2)
+}
+
+func TestPlan_Execute_WithEventHandler(t *testing.T) {
+	type event struct {
+		Type    EventType
+		Step    int
+		Name    string
+		Phase   Phase
+		Attempt int
+		Err     bool
+	}
+
+	var actual []event
+
+	plan, err := NewPlan(
+		PlanOptContinueOnError(),
+		PlanOptEventHandler(func(e Event) {
+			require.False(t, e.Time.IsZero())
+			require.Equal(t, 2, e.Total)
+			actual = append(actual, event{e.Type, e.Step, e.StepName, e.Phase, e.Attempt, e.Err != nil})
+		}))
+	require.NoError(t, err)
+
+	testStep := &GenericStep{
+		Name:     "b",
+		ExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+	}
+
+	require.NoError(t, plan.
+		AddStep(&GenericStep{Name: "a"}).
+		AddStep(testStep.WithRetries(1)).
+		Execute(context.Background()))
+
+	require.Equal(t, []event{
+		{EventPlanStart, -1, "", "", 0, false},
+		{EventStepStart, 0, "a", "", 1, false},
+		{EventPhaseStart, 0, "a", PhasePreExec, 1, false},
+		{EventPhaseEnd, 0, "a", PhasePreExec, 1, false},
+		{EventPhaseStart, 0, "a", PhaseExec, 1, false},
+		{EventPhaseEnd, 0, "a", PhaseExec, 1, false},
+		{EventPhaseStart, 0, "a", PhasePostExec, 1, false},
+		{EventPhaseEnd, 0, "a", PhasePostExec, 1, false},
+		{EventStepEnd, 0, "a", "", 1, false},
+		{EventStepStart, 1, "b", "", 1, false},
+		{EventPhaseStart, 1, "b", PhasePreExec, 1, false},
+		{EventPhaseEnd, 1, "b", PhasePreExec, 1, false},
+		{EventPhaseStart, 1, "b", PhaseExec, 1, false},
+		{EventPhaseEnd, 1, "b", PhaseExec, 1, true},
+		{EventStepRetry, 1, "b", "", 1, true},
+		{EventPhaseStart, 1, "b", PhasePreExec, 2, false},
+		{EventPhaseEnd, 1, "b", PhasePreExec, 2, false},
+		{EventPhaseStart, 1, "b", PhaseExec, 2, false},
+		{EventPhaseEnd, 1, "b", PhaseExec, 2, true},
+		{EventPhaseStart, 1, "b", PhasePostExec, 2, false},
+		{EventPhaseEnd, 1, "b", PhasePostExec, 2, false},
+		{EventStepEnd, 1, "b", "", 2, false},
+		{EventPhaseStart, 1, "b", PhaseCleanup, 1, false},
+		{EventPhaseEnd, 1, "b", PhaseCleanup, 1, false},
+		{EventPhaseStart, 0, "a", PhaseCleanup, 1, false},
+		{EventPhaseEnd, 0, "a", PhaseCleanup, 1, false},
+		{EventPlanEnd, -1, "", "", 0, false},
+	}, actual)
+}
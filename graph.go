@@ -0,0 +1,181 @@
+package gsd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GraphOpt represents a plan graph rendering option.
+type GraphOpt func(*graphConfig)
+
+type graphConfig struct {
+	outcomeColors bool
+}
+
+// GraphOptOutcomeColors instructs the plan graph rendering to color the steps
+// according to their outcome during the latest plan execution.
+func GraphOptOutcomeColors() GraphOpt {
+	return func(c *graphConfig) {
+		c.outcomeColors = true
+	}
+}
+
+// graphNode represents a plan step in a plan graph.
+type graphNode struct {
+	id     string
+	label  string
+	pause  bool
+	status StepStatus
+}
+
+// graphNodes returns the nodes of the plan's graph, in execution order.
+func (p *Plan) graphNodes() []graphNode {
+	var (
+		results = p.Results()
+		nodes   = make([]graphNode, 0, p.steps.Len())
+	)
+
+	i := 0
+	for s := p.steps.Front(); s != nil; s, i = s.Next(), i+1 {
+		step := s.Value.(Step)
+
+		node := graphNode{
+			id:    fmt.Sprintf("s%d", i),
+			label: NameOf(step),
+		}
+
+		if node.label == "" {
+			node.label = fmt.Sprintf("step %d", i+1)
+		}
+
+		node.pause = isPauseStep(step)
+
+		if i < len(results) {
+			node.status = results[i].Status
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+var dotOutcomeColors = map[StepStatus]string{
+	StepNotRun:    "lightgray",
+	StepRunning:   "lightblue",
+	StepSucceeded: "palegreen",
+	StepFailed:    "lightcoral",
+	StepSkipped:   "lightyellow",
+}
+
+// WriteDOT writes the plan's structure to w in the Graphviz DOT format.
+func (p *Plan) WriteDOT(w io.Writer, opts ...GraphOpt) error {
+	var config graphConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	nodes := p.graphNodes()
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph plan {")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+
+	for _, node := range nodes {
+		var (
+			attrs  = []string{"label=" + dotQuote(node.label)}
+			styles []string
+		)
+
+		if node.pause {
+			attrs = append(attrs, "shape=ellipse")
+			styles = append(styles, "dashed")
+		}
+
+		if config.outcomeColors {
+			attrs = append(attrs, "fillcolor="+dotQuote(dotOutcomeColors[node.status]))
+			styles = append(styles, "filled")
+		}
+
+		if len(styles) > 0 {
+			attrs = append(attrs, "style="+dotQuote(strings.Join(styles, ",")))
+		}
+
+		fmt.Fprintf(bw, "\t%s [%s];\n", node.id, strings.Join(attrs, ", "))
+	}
+
+	for i := 1; i < len(nodes); i++ {
+		fmt.Fprintf(bw, "\t%s -> %s;\n", nodes[i-1].id, nodes[i].id)
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// dotQuote returns the string s as a quoted DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+var mermaidOutcomeColors = map[StepStatus]string{
+	StepNotRun:    "#d3d3d3",
+	StepRunning:   "#add8e6",
+	StepSucceeded: "#98fb98",
+	StepFailed:    "#f08080",
+	StepSkipped:   "#ffffe0",
+}
+
+// WriteMermaid writes the plan's structure to w as a Mermaid flowchart.
+func (p *Plan) WriteMermaid(w io.Writer, opts ...GraphOpt) error {
+	var config graphConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	nodes := p.graphNodes()
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "flowchart TD")
+
+	for _, node := range nodes {
+		label := strings.ReplaceAll(node.label, `"`, "#quot;")
+
+		if node.pause {
+			fmt.Fprintf(bw, "\t%s([\"%s\"])\n", node.id, label)
+		} else {
+			fmt.Fprintf(bw, "\t%s[\"%s\"]\n", node.id, label)
+		}
+	}
+
+	for i := 1; i < len(nodes); i++ {
+		fmt.Fprintf(bw, "\t%s --> %s\n", nodes[i-1].id, nodes[i].id)
+	}
+
+	if config.outcomeColors && len(nodes) > 0 {
+		classes := make(map[StepStatus][]string)
+		for _, node := range nodes {
+			classes[node.status] = append(classes[node.status], node.id)
+		}
+
+		for _, status := range []StepStatus{StepNotRun, StepRunning, StepSucceeded, StepFailed, StepSkipped} {
+			if len(classes[status]) == 0 {
+				continue
+			}
+			fmt.Fprintf(bw, "\tclassDef %s fill:%s\n", mermaidClass(status), mermaidOutcomeColors[status])
+			fmt.Fprintf(bw, "\tclass %s %s\n", strings.Join(classes[status], ","), mermaidClass(status))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// mermaidClass returns the Mermaid class name corresponding to the step
+// status s.
+func mermaidClass(s StepStatus) string {
+	return strings.ReplaceAll(s.String(), "-", "")
+}
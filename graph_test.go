@@ -0,0 +1,80 @@
+package gsd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testGraphPlan(t *testing.T) *Plan {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	return plan.
+		AddStep(&GenericStep{Name: `say "hello"`}).
+		AddPause(time.Millisecond).
+		AddStep(&GenericStep{ExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") }}).
+		AddStep(&GenericStep{Name: "never"})
+}
+
+func TestPlan_WriteDOT(t *testing.T) {
+	plan := testGraphPlan(t)
+
+	var out bytes.Buffer
+	require.NoError(t, plan.WriteDOT(&out))
+	require.Equal(t, `digraph plan {
+	node [shape=box];
+	s0 [label="say \"hello\""];
+	s1 [label="pause 1ms", shape=ellipse, style="dashed"];
+	s2 [label="step 3"];
+	s3 [label="never"];
+	s0 -> s1;
+	s1 -> s2;
+	s2 -> s3;
+}
+`, out.String())
+
+	require.Error(t, plan.Execute(context.Background()))
+
+	out.Reset()
+	require.NoError(t, plan.WriteDOT(&out, GraphOptOutcomeColors()))
+	require.Equal(t, `digraph plan {
+	node [shape=box];
+	s0 [label="say \"hello\"", fillcolor="palegreen", style="filled"];
+	s1 [label="pause 1ms", shape=ellipse, fillcolor="palegreen", style="dashed,filled"];
+	s2 [label="step 3", fillcolor="lightcoral", style="filled"];
+	s3 [label="never", fillcolor="lightgray", style="filled"];
+	s0 -> s1;
+	s1 -> s2;
+	s2 -> s3;
+}
+`, out.String())
+}
+
+func TestPlan_WriteMermaid(t *testing.T) {
+	plan := testGraphPlan(t)
+
+	var out bytes.Buffer
+	require.NoError(t, plan.WriteMermaid(&out))
+	require.Equal(t, `flowchart TD
+	s0["say #quot;hello#quot;"]
+	s1(["pause 1ms"])
+	s2["step 3"]
+	s3["never"]
+	s0 --> s1
+	s1 --> s2
+	s2 --> s3
+`, out.String())
+
+	require.Error(t, plan.Execute(context.Background()))
+
+	out.Reset()
+	require.NoError(t, plan.WriteMermaid(&out, GraphOptOutcomeColors()))
+	require.Contains(t, out.String(), "\tclassDef succeeded fill:#98fb98\n\tclass s0,s1 succeeded\n")
+	require.Contains(t, out.String(), "\tclassDef failed fill:#f08080\n\tclass s2 failed\n")
+	require.Contains(t, out.String(), "\tclassDef notrun fill:#d3d3d3\n\tclass s3 notrun\n")
+}
@@ -0,0 +1,139 @@
+// Package gsdtest provides helpers for testing code built upon gsd plans.
+package gsdtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/falzm/gsd"
+)
+
+// Clock is a fake gsd.Clock implementation whose time only moves forward
+// when advanced manually, allowing to test plans relying on time (pauses,
+// duration limits...) deterministically and without waiting:
+//
+//	clock := gsdtest.NewClock(time.Now())
+//	plan, _ := gsd.NewPlan(gsd.PlanOptClock(clock))
+//	plan.AddPause(time.Hour)
+//
+//	go func() {
+//		clock.WaitTimers(1)
+//		clock.Advance(time.Hour)
+//	}()
+//
+//	err := plan.Execute(ctx)
+type Clock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*timer
+}
+
+// NewClock returns a new fake clock set at time t.
+func NewClock(t time.Time) *Clock {
+	c := Clock{now: t}
+	c.cond = sync.NewCond(&c.mu)
+
+	return &c
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer returns a new timer firing once the clock has been advanced by at
+// least the duration d.
+func (c *Clock) NewTimer(d time.Duration) gsd.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := timer{
+		clock:    c,
+		deadline: c.now.Add(d),
+		c:        make(chan time.Time, 1),
+	}
+
+	if d <= 0 {
+		t.c <- c.now
+		return &t
+	}
+
+	c.timers = append(c.timers, &t)
+	c.cond.Broadcast()
+
+	return &t
+}
+
+// Advance moves the clock forward by the duration d, firing the timers
+// expiring in the meantime.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].deadline.Before(c.timers[j].deadline) })
+
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+
+	c.cond.Broadcast()
+}
+
+// Timers returns the number of pending timers, i.e. created but not expired
+// nor stopped yet.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// WaitTimers blocks until at least n timers are pending. It is typically used
+// to wait until the code under test waits on the clock before advancing it.
+func (c *Clock) WaitTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// timer is a gsd.Timer implementation used by the fake Clock.
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			t.clock.cond.Broadcast()
+			return true
+		}
+	}
+
+	return false
+}
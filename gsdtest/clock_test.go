@@ -0,0 +1,60 @@
+package gsdtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falzm/gsd"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	require.Equal(t, start, clock.Now())
+
+	t1 := clock.NewTimer(time.Minute)
+	t2 := clock.NewTimer(time.Hour)
+	t3 := clock.NewTimer(time.Second)
+	require.Equal(t, 3, clock.Timers())
+
+	require.True(t, t3.Stop())
+	require.False(t, t3.Stop())
+
+	clock.Advance(time.Minute)
+	require.Equal(t, start.Add(time.Minute), clock.Now())
+	require.Equal(t, start.Add(time.Minute), <-t1.C())
+	require.Equal(t, 1, clock.Timers())
+	require.False(t, t1.Stop())
+
+	select {
+	case <-t2.C():
+		t.Fatal("timer should not have fired")
+	default:
+	}
+
+	require.Equal(t, start, <-NewClock(start).NewTimer(0).C())
+}
+
+func TestClock_Plan(t *testing.T) {
+	clock := NewClock(time.Now())
+
+	plan, err := gsd.NewPlan(gsd.PlanOptClock(clock), gsd.PlanOptLimitDuration(2*time.Hour))
+	require.NoError(t, err)
+
+	go func() {
+		// Duration limit + pause timers.
+		clock.WaitTimers(2)
+		clock.Advance(time.Hour)
+		clock.WaitTimers(2)
+		clock.Advance(time.Hour)
+	}()
+
+	err = plan.
+		AddPause(time.Hour).
+		AddPause(3 * time.Hour).
+		Execute(context.Background())
+	require.Equal(t, gsd.ErrTimeout, err)
+}
@@ -0,0 +1,239 @@
+package gsdtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/falzm/gsd"
+)
+
+// Call represents a recorded call of a step hook.
+type Call struct {
+	// Step is the name of the step.
+	Step string
+
+	// Phase is the step hook called.
+	Phase gsd.Phase
+
+	// N is the number of times (starting at 1) the step hook has been
+	// called, e.g. 2 for the second attempt of a step's Exec hook.
+	N int
+
+	// Ctx is the context passed to the hook.
+	Ctx context.Context
+
+	// State is the plan state passed to the hook.
+	State *gsd.State
+
+	// Err is the error returned by the hook.
+	Err error
+}
+
+// String returns the call as "<step>:<phase>", the format expected by the
+// AssertPhaseOrder function.
+func (c Call) String() string {
+	return c.Step + ":" + string(c.Phase)
+}
+
+// Recorder records the hook calls of the steps attached to it, in the order
+// they occur.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewRecorder returns a new recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Calls returns the recorded calls, in the order they occurred.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]Call, len(r.calls))
+	copy(calls, r.calls)
+
+	return calls
+}
+
+// record records the call c.
+func (r *Recorder) record(c Call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, c)
+}
+
+// count returns the number of recorded calls of the phase hook of the step
+// named name.
+func (r *Recorder) count(name string, phase gsd.Phase) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, c := range r.calls {
+		if c.Step == name && c.Phase == phase {
+			n++
+		}
+	}
+
+	return n
+}
+
+// RecorderStep is a gsd.Step implementation recording every call of its
+// hooks in a Recorder. Its hooks always succeed, see MockStep for scripted
+// failures.
+type RecorderStep struct {
+	name     string
+	retries  int
+	recorder *Recorder
+	script   map[gsd.Phase]map[int]error
+}
+
+// NewRecorderStep returns a new recorder step named name recording its hooks
+// calls in r.
+func NewRecorderStep(r *Recorder, name string) *RecorderStep {
+	return &RecorderStep{name: name, recorder: r}
+}
+
+// WithRetries sets the number of retries of the step.
+func (s *RecorderStep) WithRetries(n int) *RecorderStep {
+	s.retries = n
+	return s
+}
+
+func (s *RecorderStep) call(ctx context.Context, state *gsd.State, phase gsd.Phase) error {
+	c := Call{
+		Step:  s.name,
+		Phase: phase,
+		N:     s.recorder.count(s.name, phase) + 1,
+		Ctx:   ctx,
+		State: state,
+	}
+
+	c.Err = s.script[phase][c.N]
+	s.recorder.record(c)
+
+	return c.Err
+}
+
+func (s *RecorderStep) PreExec(ctx context.Context, state *gsd.State) error {
+	return s.call(ctx, state, gsd.PhasePreExec)
+}
+
+func (s *RecorderStep) Exec(ctx context.Context, state *gsd.State) error {
+	return s.call(ctx, state, gsd.PhaseExec)
+}
+
+func (s *RecorderStep) PostExec(ctx context.Context, state *gsd.State) error {
+	return s.call(ctx, state, gsd.PhasePostExec)
+}
+
+func (s *RecorderStep) Cleanup(ctx context.Context, state *gsd.State) {
+	_ = s.call(ctx, state, gsd.PhaseCleanup)
+}
+
+func (s *RecorderStep) Retries() int {
+	return s.retries
+}
+
+func (s *RecorderStep) StepName() string {
+	return s.name
+}
+
+// MockStep is a RecorderStep whose hooks can be scripted to fail at given
+// calls, e.g. to fail the Exec hook on the first attempt then succeed:
+//
+//	step := gsdtest.NewMockStep(rec, "flaky").
+//		FailOn(gsd.PhaseExec, 1, errors.New("boom")).
+//		WithRetries(1)
+type MockStep struct {
+	*RecorderStep
+}
+
+// NewMockStep returns a new mock step named name recording its hooks calls
+// in r.
+func NewMockStep(r *Recorder, name string) *MockStep {
+	return &MockStep{
+		RecorderStep: &RecorderStep{
+			name:     name,
+			recorder: r,
+			script:   make(map[gsd.Phase]map[int]error),
+		},
+	}
+}
+
+// FailOn instructs the step's phase hook to return the error err on its n-th
+// call (starting at 1). The error is ignored for the PhaseCleanup phase, as
+// the Cleanup hook cannot fail.
+func (s *MockStep) FailOn(phase gsd.Phase, n int, err error) *MockStep {
+	if s.script[phase] == nil {
+		s.script[phase] = make(map[int]error)
+	}
+	s.script[phase][n] = err
+
+	return s
+}
+
+// WithRetries sets the number of retries of the step.
+func (s *MockStep) WithRetries(n int) *MockStep {
+	s.retries = n
+	return s
+}
+
+// AssertPhaseOrder asserts that the calls recorded by r match exactly the
+// expected calls, formatted as "<step>:<phase>" (e.g. "build:pre-exec").
+func AssertPhaseOrder(t testing.TB, r *Recorder, expected ...string) bool {
+	t.Helper()
+
+	calls := r.Calls()
+	actual := make([]string, len(calls))
+	for i, c := range calls {
+		actual[i] = c.String()
+	}
+
+	if !equalStrings(actual, expected) {
+		t.Errorf("unexpected phase order:\nexpected: %q\nactual:   %q", expected, actual)
+		return false
+	}
+
+	return true
+}
+
+// AssertCleanedUp asserts that the Cleanup hook of the steps named steps has
+// been called exactly once, in the specified order, and that no other step
+// has been cleaned up.
+func AssertCleanedUp(t testing.TB, r *Recorder, steps ...string) bool {
+	t.Helper()
+
+	var actual []string
+	for _, c := range r.Calls() {
+		if c.Phase == gsd.PhaseCleanup {
+			actual = append(actual, c.Step)
+		}
+	}
+
+	if !equalStrings(actual, steps) {
+		t.Errorf("unexpected cleaned up steps:\nexpected: %q\nactual:   %q", steps, actual)
+		return false
+	}
+
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
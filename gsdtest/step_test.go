@@ -0,0 +1,87 @@
+package gsdtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falzm/gsd"
+)
+
+func TestRecorderStep(t *testing.T) {
+	rec := NewRecorder()
+
+	plan, err := gsd.NewPlan()
+	require.NoError(t, err)
+
+	require.NoError(t, plan.
+		AddStep(NewRecorderStep(rec, "a")).
+		AddStep(NewRecorderStep(rec, "b")).
+		Execute(context.Background()))
+
+	AssertPhaseOrder(t, rec,
+		"a:pre-exec", "a:exec", "a:post-exec",
+		"b:pre-exec", "b:exec", "b:post-exec",
+		"b:cleanup", "a:cleanup")
+	AssertCleanedUp(t, rec, "b", "a")
+
+	calls := rec.Calls()
+	require.Equal(t, plan.State(), calls[0].State)
+	require.NotNil(t, calls[0].Ctx)
+	require.Equal(t, 1, calls[0].N)
+}
+
+func TestMockStep(t *testing.T) {
+	rec := NewRecorder()
+
+	plan, err := gsd.NewPlan(gsd.PlanOptContinueOnError())
+	require.NoError(t, err)
+
+	require.NoError(t, plan.
+		AddStep(NewMockStep(rec, "flaky").
+			FailOn(gsd.PhaseExec, 1, errors.New("blah")).
+			WithRetries(1)).
+		Execute(context.Background()))
+
+	AssertPhaseOrder(t, rec,
+		"flaky:pre-exec", "flaky:exec",
+		"flaky:pre-exec", "flaky:exec", "flaky:post-exec",
+		"flaky:cleanup")
+
+	calls := rec.Calls()
+	require.EqualError(t, calls[1].Err, "blah")
+	require.NoError(t, calls[3].Err)
+	require.Equal(t, 2, calls[3].N)
+}
+
+func TestAssertions(t *testing.T) {
+	rec := NewRecorder()
+
+	plan, err := gsd.NewPlan()
+	require.NoError(t, err)
+
+	require.Error(t, plan.
+		AddStep(NewRecorderStep(rec, "a")).
+		AddStep(NewMockStep(rec, "b").FailOn(gsd.PhasePreExec, 1, errors.New("blah"))).
+		Execute(context.Background()))
+
+	require.True(t, AssertCleanedUp(t, rec, "a"))
+
+	mock := &mockTB{TB: t}
+	require.False(t, AssertCleanedUp(mock, rec, "b", "a"))
+	require.False(t, AssertPhaseOrder(mock, rec, "a:pre-exec"))
+	require.Equal(t, 2, mock.errors)
+}
+
+type mockTB struct {
+	testing.TB
+	errors int
+}
+
+func (m *mockTB) Helper() {}
+
+func (m *mockTB) Errorf(string, ...interface{}) {
+	m.errors++
+}
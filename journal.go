@@ -0,0 +1,76 @@
+package gsd
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// JournalVersion is the version of the plan execution journal records
+// format. It is incremented upon incompatible changes to the format.
+const JournalVersion = 1
+
+// JournalRecord represents a plan execution journal record.
+type JournalRecord struct {
+	// Version is the version of the record format (see JournalVersion).
+	Version int `json:"v"`
+
+	// Time is the time at which the event occurred.
+	Time time.Time `json:"time"`
+
+	// Event is the type of the event (see EventType).
+	Event string `json:"event"`
+
+	// Step is the index (starting at 0) of the step the event relates to,
+	// absent for plan-level events.
+	Step *int `json:"step,omitempty"`
+
+	// StepName is the name of the step the event relates to (if any).
+	StepName string `json:"step_name,omitempty"`
+
+	// Phase is the step execution phase the event relates to (if any).
+	Phase Phase `json:"phase,omitempty"`
+
+	// Attempt is the attempt number (starting at 1) of the step execution
+	// the event relates to (if any).
+	Attempt int `json:"attempt,omitempty"`
+
+	// Error is the error reported by the plan, step or phase (if any).
+	Error string `json:"error,omitempty"`
+}
+
+// PlanOptJournal instructs the plan to write a journal of its execution to
+// w in the JSON Lines format, i.e. one JournalRecord JSON object per line for
+// every execution event. Note: errors returned by w are ignored, as they
+// cannot interrupt the plan execution.
+func PlanOptJournal(w io.Writer) PlanOpt {
+	var (
+		mu  sync.Mutex
+		enc = json.NewEncoder(w)
+	)
+
+	return PlanOptEventHandler(func(e Event) {
+		record := JournalRecord{
+			Version:  JournalVersion,
+			Time:     e.Time,
+			Event:    e.Type.String(),
+			StepName: e.StepName,
+			Phase:    e.Phase,
+			Attempt:  e.Attempt,
+		}
+
+		if e.Step >= 0 {
+			step := e.Step
+			record.Step = &step
+		}
+
+		if e.Err != nil {
+			record.Error = e.Err.Error()
+		}
+
+		mu.Lock()
+		_ = enc.Encode(record)
+		mu.Unlock()
+	})
+}
@@ -0,0 +1,50 @@
+package gsd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanOptJournal(t *testing.T) {
+	var out bytes.Buffer
+
+	plan, err := NewPlan(PlanOptJournal(&out))
+	require.NoError(t, err)
+
+	require.Error(t, plan.
+		AddStep(&GenericStep{
+			Name:        "a",
+			PreExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+		}).
+		Execute(context.Background()))
+
+	var records []JournalRecord
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record JournalRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		require.Equal(t, JournalVersion, record.Version)
+		require.False(t, record.Time.IsZero())
+		records = append(records, record)
+	}
+
+	step := 0
+	for i := range records {
+		records[i].Version = 0
+		records[i].Time = records[0].Time
+	}
+	require.Equal(t, []JournalRecord{
+		{Time: records[0].Time, Event: "plan-start"},
+		{Time: records[0].Time, Event: "step-start", Step: &step, StepName: "a", Attempt: 1},
+		{Time: records[0].Time, Event: "phase-start", Step: &step, StepName: "a", Phase: PhasePreExec, Attempt: 1},
+		{Time: records[0].Time, Event: "phase-end", Step: &step, StepName: "a", Phase: PhasePreExec, Attempt: 1, Error: "blah"},
+		{Time: records[0].Time, Event: "step-end", Step: &step, StepName: "a", Attempt: 1, Error: "blah"},
+		{Time: records[0].Time, Event: "plan-end", Error: "blah"},
+	}, records)
+}
@@ -0,0 +1,86 @@
+package gsd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// evalJSONPath returns the value designated by the JSONPath expression path
+// in the decoded JSON document doc. Only a subset of JSONPath is supported:
+// the root object "$" followed by child members (".name" or "['name']") and
+// array indices ("[0]", negative indices counting from the end).
+func evalJSONPath(doc interface{}, path string) (interface{}, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSON path %q: must start with $", path)
+	}
+
+	v, rest := doc, path[1:]
+
+	for rest != "" {
+		var (
+			member string
+			index  int
+			isIdx  bool
+		)
+
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			member, rest = rest[1:end+1], rest[end+1:]
+			if member == "" {
+				return nil, fmt.Errorf("invalid JSON path %q: empty member name", path)
+			}
+
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: unterminated bracket", path)
+			}
+			sel := rest[1:end]
+			rest = rest[end+1:]
+
+			if len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0] {
+				member = sel[1 : len(sel)-1]
+				break
+			}
+
+			i, err := strconv.Atoi(sel)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON path %q: invalid index %q", path, sel)
+			}
+			index, isIdx = i, true
+
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q: unexpected character %q", path, rest[0])
+		}
+
+		if isIdx {
+			a, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("JSON path %q: cannot index %T value", path, v)
+			}
+			if index < 0 {
+				index += len(a)
+			}
+			if index < 0 || index >= len(a) {
+				return nil, fmt.Errorf("JSON path %q: index %d out of range", path, index)
+			}
+			v = a[index]
+			continue
+		}
+
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("JSON path %q: cannot get member %q of %T value", path, member, v)
+		}
+		if v, ok = o[member]; !ok {
+			return nil, fmt.Errorf("JSON path %q: member %q not found", path, member)
+		}
+	}
+
+	return v, nil
+}
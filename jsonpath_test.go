@@ -0,0 +1,44 @@
+package gsd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"a":{"b c":[1,{"d":true}]},"e":"f"}`), &doc))
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		err      bool
+	}{
+		{path: "$", expected: doc},
+		{path: "$.e", expected: "f"},
+		{path: `$.a["b c"][0]`, expected: float64(1)},
+		{path: "$.a['b c'][1].d", expected: true},
+		{path: "$.a['b c'][-2]", expected: float64(1)},
+		{path: "$.a['b c'][2]", err: true},
+		{path: "$.x", err: true},
+		{path: "$.e.f", err: true},
+		{path: "$.e[0]", err: true},
+		{path: "$.a[", err: true},
+		{path: "$..e", err: true},
+		{path: "e", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			v, err := evalJSONPath(doc, tt.path)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, v)
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package gsd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadError represents an error reported when loading an invalid plan
+// definition.
+type LoadError struct {
+	// Line is the line (starting at 1) of the plan definition the error
+	// relates to.
+	Line int
+
+	// Step is the index (starting at 1) of the step the error relates to, or
+	// 0 if the error doesn't relate to a step.
+	Step int
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *LoadError) Error() string {
+	if e.Step > 0 {
+		return fmt.Sprintf("line %d: step %d: %v", e.Line, e.Step, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// StepParams represents the parameters of a step in a plan definition.
+type StepParams struct {
+	node *yaml.Node
+}
+
+// Decode decodes the parameters into v, which must be a pointer, following
+// the gopkg.in/yaml.v3 package decoding rules. If v points to a struct,
+// parameters not matching any of its fields are reported as errors. It
+// returns a *LoadError error if the parameters are invalid.
+func (p StepParams) Decode(v interface{}) error {
+	if p.node == nil {
+		return nil
+	}
+
+	if err := checkKnownFields(p.node, v); err != nil {
+		return err
+	}
+
+	if err := p.node.Decode(v); err != nil {
+		return yamlLoadError(p.node.Line, err)
+	}
+
+	return nil
+}
+
+// StepFactory represents a function returning a new step configured with the
+// parameters params.
+type StepFactory func(params StepParams) (Step, error)
+
+// Registry maps step type names to step factories, allowing to load plan
+// definitions. The zero value is not usable, use NewRegistry to create a
+// registry.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]StepFactory
+}
+
+// DefaultRegistry is the Registry used by LoadPlan when no registry is
+// specified.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a new registry, in which the built-in steps are
+// registered. Their parameters are their exported fields in snake case
+// (e.g. "expect_status" for HTTPStep.ExpectStatus):
+//
+//   - approval: ApprovalStep, with a stdin approver
+//   - chmod: ChmodStep
+//   - command: CommandStep
+//   - copy_file: CopyFileStep
+//   - http: HTTPStep
+//   - mkdir: MkdirStep
+//   - move_file: MoveFileStep
+//   - pause: a pause step (see Plan.AddPause), with a "duration" parameter
+//   - symlink: SymlinkStep
+//   - template_file: TemplateFileStep
+//   - write_file: WriteFileStep, with a string "content" parameter
+func NewRegistry() *Registry {
+	r := Registry{factories: make(map[string]StepFactory)}
+
+	for name, f := range map[string]StepFactory{
+		"approval": func(params StepParams) (Step, error) {
+			s := ApprovalStep{Approver: NewStdinApprover()}
+			return &s, params.Decode(&s)
+		},
+		"chmod":         newStepFactory(func() Step { return &ChmodStep{} }),
+		"command":       newStepFactory(func() Step { return &CommandStep{} }),
+		"copy_file":     newStepFactory(func() Step { return &CopyFileStep{} }),
+		"http":          newStepFactory(func() Step { return &HTTPStep{} }),
+		"mkdir":         newStepFactory(func() Step { return &MkdirStep{} }),
+		"move_file":     newStepFactory(func() Step { return &MoveFileStep{} }),
+		"symlink":       newStepFactory(func() Step { return &SymlinkStep{} }),
+		"template_file": newStepFactory(func() Step { return &TemplateFileStep{} }),
+		"pause": func(params StepParams) (Step, error) {
+			var p struct {
+				Duration time.Duration `yaml:"duration"`
+			}
+			if err := params.Decode(&p); err != nil {
+				return nil, err
+			}
+			return &pauseStep{d: p.Duration}, nil
+		},
+		"write_file": func(params StepParams) (Step, error) {
+			var p struct {
+				WriteFileStep `yaml:",inline"`
+				Content       string `yaml:"content"`
+			}
+			if err := params.Decode(&p); err != nil {
+				return nil, err
+			}
+			p.WriteFileStep.Content = []byte(p.Content)
+			return &p.WriteFileStep, nil
+		},
+	} {
+		r.factories[name] = f
+	}
+
+	return &r
+}
+
+// newStepFactory returns a step factory decoding the parameters into the
+// step returned by newStep.
+func newStepFactory(newStep func() Step) StepFactory {
+	return func(params StepParams) (Step, error) {
+		s := newStep()
+		if err := params.Decode(s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+// Register registers the step factory f for the step type name.
+func (r *Registry) Register(name string, f StepFactory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("step type %q already registered", name)
+	}
+
+	r.factories[name] = f
+
+	return nil
+}
+
+func (r *Registry) factory(name string) (StepFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.factories[name]
+	return f, ok
+}
+
+type planDefinition struct {
+	ContinueOnError bool          `yaml:"continue_on_error"`
+	LimitDuration   time.Duration `yaml:"limit_duration"`
+	Steps           []yaml.Node   `yaml:"steps"`
+}
+
+type stepDefinition struct {
+	Type    string    `yaml:"type"`
+	Name    string    `yaml:"name"`
+	Retries *int      `yaml:"retries"`
+	Tags    []string  `yaml:"tags"`
+	Params  yaml.Node `yaml:"params"`
+}
+
+// LoadPlan returns a new plan created with the options opts, and whose steps
+// and options are defined by the YAML or JSON document read from r. The
+// steps are created using the step factories of the registry reg (or
+// DefaultRegistry if nil):
+//
+//	continue_on_error: true  # PlanOptContinueOnError
+//	limit_duration: 10m      # PlanOptLimitDuration
+//	steps:
+//	  - type: command        # step type, as registered in the registry
+//	    name: build          # optional step name
+//	    retries: 2           # optional number of retries
+//	    tags: [build]        # optional step tags
+//	    params:              # step parameters, passed to the step factory
+//	      command: make
+//
+// It returns a *LoadError error if the plan definition is invalid.
+func LoadPlan(r io.Reader, reg *Registry, opts ...PlanOpt) (*Plan, error) {
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &LoadError{Line: 1, Err: errors.New("empty plan definition")}
+		}
+		return nil, yamlLoadError(1, err)
+	}
+
+	root := doc.Content[0]
+
+	var def planDefinition
+	if err := checkKnownFields(root, &def); err != nil {
+		return nil, err
+	}
+	if err := root.Decode(&def); err != nil {
+		return nil, yamlLoadError(root.Line, err)
+	}
+
+	if def.ContinueOnError {
+		opts = append(opts, PlanOptContinueOnError())
+	}
+	if def.LimitDuration > 0 {
+		opts = append(opts, PlanOptLimitDuration(def.LimitDuration))
+	}
+
+	plan, err := NewPlan(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range def.Steps {
+		step, err := loadStep(&def.Steps[i], reg)
+		if err != nil {
+			var lerr *LoadError
+			if !errors.As(err, &lerr) {
+				lerr = &LoadError{Line: def.Steps[i].Line, Err: err}
+			}
+			lerr.Step = i + 1
+			return nil, lerr
+		}
+
+		plan.AddStep(step)
+	}
+
+	return plan, nil
+}
+
+// LoadPlanFile is a convenience function calling LoadPlan with the content
+// of the file at path.
+func LoadPlanFile(path string, reg *Registry, opts ...PlanOpt) (*Plan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	plan, err := LoadPlan(f, reg, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return plan, nil
+}
+
+// loadStep returns the step defined by the node.
+func loadStep(node *yaml.Node, reg *Registry) (Step, error) {
+	var def stepDefinition
+	if err := checkKnownFields(node, &def); err != nil {
+		return nil, err
+	}
+	if err := node.Decode(&def); err != nil {
+		return nil, yamlLoadError(node.Line, err)
+	}
+
+	if def.Type == "" {
+		return nil, errors.New("missing step type")
+	}
+
+	factory, ok := reg.factory(def.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown step type %q", def.Type)
+	}
+
+	var params StepParams
+	if def.Params.Kind != 0 {
+		params.node = &def.Params
+	}
+
+	step, err := factory(params)
+	if err != nil {
+		return nil, err
+	}
+	if step == nil {
+		return nil, fmt.Errorf("step type %q factory returned no step", def.Type)
+	}
+
+	if def.Name == "" && def.Retries == nil && def.Tags == nil {
+		return step, nil
+	}
+
+	return &loadedStep{
+		WrappedStep: WrappedStep{Step: step},
+		name:        def.Name,
+		retries:     def.Retries,
+		tags:        def.Tags,
+	}, nil
+}
+
+// loadedStep is a Step wrapper overriding the name, number of retries and
+// tags of the wrapped step with the values of its definition.
+type loadedStep struct {
+	WrappedStep
+	name    string
+	retries *int
+	tags    []string
+}
+
+func (s *loadedStep) StepName() string {
+	if s.name != "" {
+		return s.name
+	}
+
+	return NameOf(s.Step)
+}
+
+func (s *loadedStep) StepTags() []string {
+	if s.tags != nil {
+		return s.tags
+	}
+
+	return TagsOf(s.Step)
+}
+
+func (s *loadedStep) Retries() int {
+	if s.retries != nil {
+		return *s.retries
+	}
+
+	return s.Step.Retries()
+}
+
+// checkKnownFields checks that the keys of the mapping node all match a field
+// of the struct v points to (if any).
+func checkKnownFields(node *yaml.Node, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	yamlFields(t.Elem(), known)
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !known[key.Value] {
+			names := make([]string, 0, len(known))
+			for name := range known {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			return &LoadError{
+				Line: key.Line,
+				Err:  fmt.Errorf("unknown parameter %q (expected one of: %s)", key.Value, strings.Join(names, ", ")),
+			}
+		}
+	}
+
+	return nil
+}
+
+// yamlFields adds the YAML names of the fields of the struct type t to names.
+func yamlFields(t reflect.Type, names map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		if len(tag) > 1 && tag[1] == "inline" && f.Type.Kind() == reflect.Struct {
+			yamlFields(f.Type, names)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if tag[0] != "" {
+			names[tag[0]] = true
+		} else {
+			names[strings.ToLower(f.Name)] = true
+		}
+	}
+}
+
+// yamlLoadError returns a *LoadError error from the YAML decoding error err,
+// reported at the line of the error if known, otherwise at line.
+func yamlLoadError(line int, err error) error {
+	msg := err.Error()
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	msg = strings.TrimPrefix(msg, "yaml: ")
+
+	if strings.HasPrefix(msg, "line ") {
+		if i := strings.IndexByte(msg, ':'); i > 0 {
+			if n, err := strconv.Atoi(msg[len("line "):i]); err == nil {
+				line, msg = n, strings.TrimSpace(msg[i+1:])
+			}
+		}
+	}
+
+	return &LoadError{Line: line, Err: errors.New(msg)}
+}
@@ -0,0 +1,161 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPlan(t *testing.T) {
+	dir := t.TempDir()
+
+	reg := NewRegistry()
+	require.NoError(t, reg.Register("greet", func(params StepParams) (Step, error) {
+		var p struct {
+			Who string `yaml:"who"`
+		}
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		if p.Who == "" {
+			return nil, errors.New("missing who")
+		}
+		return &GenericStep{
+			Name:     "greet " + p.Who,
+			ExecFunc: func(ctx context.Context, state *State) error { return testStepFunc(state, "hello "+p.Who) },
+		}, nil
+	}))
+	require.Error(t, reg.Register("greet", nil))
+
+	plan, err := LoadPlan(strings.NewReader(`
+continue_on_error: true
+limit_duration: 1m
+steps:
+  - type: greet
+    params:
+      who: world
+  - type: mkdir
+    name: create dir
+    tags: [fs]
+    params:
+      path: `+filepath.Join(dir, "a")+`
+  - type: write_file
+    retries: 2
+    params:
+      path: `+filepath.Join(dir, "a", "hello.txt")+`
+      content: hello
+      mode: 0600
+  - type: pause
+    params:
+      duration: 1ms
+`), reg)
+	require.NoError(t, err)
+	require.True(t, plan.continueOnError)
+	require.Equal(t, time.Minute, plan.maxDuration)
+
+	steps := make([]Step, 0)
+	for s := plan.steps.Front(); s != nil; s = s.Next() {
+		steps = append(steps, s.Value.(Step))
+	}
+	require.Len(t, steps, 4)
+	require.Equal(t, "greet world", NameOf(steps[0]))
+	require.Equal(t, "create dir", NameOf(steps[1]))
+	require.Equal(t, []string{"fs"}, TagsOf(steps[1]))
+	require.Equal(t, "write "+filepath.Join(dir, "a", "hello.txt"), NameOf(steps[2]))
+	require.Equal(t, 2, steps[2].Retries())
+	require.True(t, isPauseStep(steps[3]))
+
+	require.NoError(t, plan.Execute(context.Background()))
+	require.Equal(t, "hello world", plan.State().Get("test"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "a", "hello.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+
+	// JSON documents are supported as well.
+	plan, err = LoadPlan(strings.NewReader(`{"steps": [{"type": "greet", "params": {"who": "json"}}]}`), reg)
+	require.NoError(t, err)
+	require.NoError(t, plan.Execute(context.Background()))
+	require.Equal(t, "hello json", plan.State().Get("test"))
+}
+
+func TestLoadPlan_Errors(t *testing.T) {
+	reg := NewRegistry()
+	require.NoError(t, reg.Register("fail", func(params StepParams) (Step, error) {
+		return nil, errors.New("factory failed")
+	}))
+
+	tests := []struct {
+		name string
+		def  string
+		err  string
+	}{
+		{
+			name: "empty",
+			def:  "",
+			err:  "line 1: empty plan definition",
+		},
+		{
+			name: "invalid syntax",
+			def:  "steps: [\n",
+			err:  "line 1: did not find expected node content",
+		},
+		{
+			// Used to crash the YAML parser (CVE-2022-28948).
+			name: "malformed document",
+			def:  "0: [:!00 \xef",
+			err:  "line 1: incomplete UTF-8 octet sequence",
+		},
+		{
+			name: "unknown plan option",
+			def:  "steps: []\ncontinue: true\n",
+			err:  `line 2: unknown parameter "continue" (expected one of: continue_on_error, limit_duration, steps)`,
+		},
+		{
+			name: "invalid plan option",
+			def:  "limit_duration: forever\n",
+			err:  "line 1: cannot unmarshal !!str `forever` into time.Duration",
+		},
+		{
+			name: "missing step type",
+			def:  "steps:\n  - name: x\n",
+			err:  "line 2: step 1: missing step type",
+		},
+		{
+			name: "unknown step type",
+			def:  "steps:\n  - type: pause\n  - type: nope\n",
+			err:  `line 3: step 2: unknown step type "nope"`,
+		},
+		{
+			name: "unknown step parameter",
+			def:  "steps:\n  - type: pause\n    params:\n      duration: 1s\n      delay: 1s\n",
+			err:  `line 5: step 1: unknown parameter "delay" (expected one of: duration)`,
+		},
+		{
+			name: "invalid step parameter",
+			def:  "steps:\n  - type: command\n    params:\n      command: ls\n      exit_codes: [0, x]\n",
+			err:  "line 5: step 1: cannot unmarshal !!str `x` into int",
+		},
+		{
+			name: "factory error",
+			def:  "steps:\n  - type: pause\n  - type: fail\n",
+			err:  "line 3: step 2: factory failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPlan(strings.NewReader(tt.def), reg)
+			require.EqualError(t, err, tt.err)
+
+			var lerr *LoadError
+			require.True(t, errors.As(err, &lerr))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package gsd
+
+// Middleware represents a function wrapping a step in order to alter or
+// extend its behavior, e.g. to time, log or serialize the execution of its
+// hooks.
+//
+// The Step returned by a middleware should implement the Wrapper interface,
+// so that the optional interfaces (e.g. Named, Weighted) implemented by the
+// original step remain visible to the plan. Embedding the WrappedStep
+// structure is the simplest way to achieve this:
+//
+//	func logging(next gsd.Step) gsd.Step {
+//		return &loggingStep{gsd.WrappedStep{Step: next}}
+//	}
+//
+//	type loggingStep struct {
+//		gsd.WrappedStep
+//	}
+//
+//	func (s *loggingStep) Exec(ctx context.Context, state *gsd.State) error {
+//		log.Println("exec")
+//		return s.Step.Exec(ctx, state)
+//	}
+type Middleware func(Step) Step
+
+// PlanOptMiddleware instructs the plan to wrap each of its steps with the
+// middlewares mw during execution. This option can be specified multiple
+// times, the middlewares being applied in the order they have been
+// specified: the first middleware is the outermost one. The plan middlewares
+// are applied on top of the step-level middlewares (see Wrap).
+func PlanOptMiddleware(mw ...Middleware) PlanOpt {
+	return func(p *Plan) error {
+		p.middlewares = append(p.middlewares, mw...)
+		return nil
+	}
+}
+
+// Wrapper is the interface implemented by steps wrapping another step, such
+// as the ones returned by middlewares.
+type Wrapper interface {
+	// Unwrap returns the wrapped step.
+	Unwrap() Step
+}
+
+// WrappedStep is a Step implementation forwarding all hooks to the wrapped
+// Step, intended to be embedded by middleware steps overriding a subset of
+// the hooks.
+type WrappedStep struct {
+	Step
+}
+
+// Unwrap returns the wrapped step.
+func (s WrappedStep) Unwrap() Step {
+	return s.Step
+}
+
+// Wrap returns the step s wrapped with the middlewares mw, the first
+// middleware being the outermost one.
+func Wrap(s Step, mw ...Middleware) Step {
+	for i := len(mw) - 1; i >= 0; i-- {
+		s = mw[i](s)
+	}
+
+	return s
+}
+
+// unwrapStep calls the function f on the step s then on each step it wraps
+// (if any), until f returns true. It returns true if f returned true for one
+// of the steps.
+func unwrapStep(s Step, f func(Step) bool) bool {
+	for s != nil {
+		if f(s) {
+			return true
+		}
+
+		w, ok := s.(Wrapper)
+		if !ok {
+			break
+		}
+		s = w.Unwrap()
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+package gsd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testMiddlewareStep struct {
+	WrappedStep
+	tag string
+}
+
+func (s *testMiddlewareStep) Exec(ctx context.Context, state *State) error {
+	_ = testStepFunc(state, "<"+s.tag)
+	err := s.Step.Exec(ctx, state)
+	_ = testStepFunc(state, s.tag+">")
+	return err
+}
+
+func testMiddleware(tag string) Middleware {
+	return func(next Step) Step {
+		return &testMiddlewareStep{WrappedStep: WrappedStep{Step: next}, tag: tag}
+	}
+}
+
+func TestPlanOptMiddleware(t *testing.T) {
+	plan := &Plan{}
+
+	require.NoError(t, PlanOptMiddleware(testMiddleware("a"))(plan))
+	require.NoError(t, PlanOptMiddleware(testMiddleware("b"), testMiddleware("c"))(plan))
+	require.Len(t, plan.middlewares, 3)
+}
+
+func TestWrap(t *testing.T) {
+	step := &GenericStep{Name: "test"}
+	step.WithWeight(3)
+
+	wrapped := Wrap(step, testMiddleware("a"), testMiddleware("b"))
+	require.Equal(t, step, wrapped.(Wrapper).Unwrap().(Wrapper).Unwrap())
+	require.Equal(t, "a", wrapped.(*testMiddlewareStep).tag)
+
+	// Optional interfaces of the original step must remain visible.
+	require.Equal(t, "test", NameOf(wrapped))
+	require.Equal(t, []float64{3}, newProgressTracker(nil, realClock{}, []Step{wrapped}).weights)
+	require.True(t, isPauseStep(Wrap(&pauseStep{}, testMiddleware("a"))))
+}
+
+func TestPlan_Execute_WithMiddleware(t *testing.T) {
+	plan, err := NewPlan(PlanOptMiddleware(testMiddleware("p")))
+	require.NoError(t, err)
+
+	require.NoError(t, plan.
+		AddStep((&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error { return testStepFunc(state, "1") },
+		}).With(testMiddleware("s"))).
+		AddPause(time.Millisecond).
+		Execute(context.Background()))
+
+	actual, _ := plan.State().Load("test")
+	require.Equal(t, "<p<s1s>p><pp>", actual)
+}
@@ -3,6 +3,7 @@ package gsd
 import (
 	"container/list"
 	"context"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -35,12 +36,24 @@ type Plan struct {
 	steps           *list.List
 	continueOnError bool
 	maxDuration     time.Duration
+	progressFunc    ProgressFunc
+	eventHandlers   []EventHandler
+	middlewares     []Middleware
+	clock           Clock
+	dryRun          bool
+	validate        bool
+	checkpointStore CheckpointStore
+	stepFilters     []stepFilter
+	dryRunReport    []DryRunAction
+	results         []StepResult
+	resultsStart    []time.Time
+	resultsMu       sync.Mutex
 }
 
 // NewPlan returns a new plan.
 func NewPlan(opts ...PlanOpt) (*Plan, error) {
 	plan := Plan{
-		state: &State{sync.Map{}},
+		state: &State{},
 		steps: list.New(),
 	}
 
@@ -66,83 +79,221 @@ func (p *Plan) AddPause(d time.Duration) *Plan {
 	return p.AddStep(&pauseStep{d: d})
 }
 
+// AddWaitUntil adds a step waiting until the condition function cond returns
+// true, polling it every interval (or every second if interval is not
+// greater than zero). The step fails with the ErrWaitTimeout
+// error if the condition doesn't hold within the duration timeout (if
+// greater than zero), or with the error returned by cond (if any).
+func (p *Plan) AddWaitUntil(
+	cond func(context.Context, *State) (bool, error),
+	interval time.Duration,
+	timeout time.Duration,
+) *Plan {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return p.AddStep(&waitStep{cond: cond, interval: interval, timeout: timeout})
+}
+
 // Execute executes the plan's steps sequentially until completion, or
 // stops and returns a non-nil error if a step failed (unless the
 // PlanOptContinueOnError option has been specified during plan creation).
+// If the PlanOptDryRun option has been specified, the steps are not executed
+// but described instead (see Plan.DryRunReport). If the PlanOptValidate
+// option has been specified, the execution is aborted before the first step
+// if the plan validation fails.
 func (p *Plan) Execute(ctx context.Context) error {
+	return p.execute(ctx, p.checkpointStore, nil, nil)
+}
+
+// execute executes the plan, recording checkpoints in the store (if not nil),
+// skipping the steps completed according to the checkpoint resume (if not
+// nil) and obeying the control requests of the run (if not nil).
+func (p *Plan) execute(ctx context.Context, store CheckpointStore, resume *Checkpoint, run *Run) error {
 	var cancel context.CancelFunc
 
+	clock := p.getClock()
+	ctx = context.WithValue(ctx, clockCtxKey{}, clock)
+
+	if p.validate {
+		if err := p.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if p.dryRun {
+		return p.execDryRun(ctx)
+	}
+
 	if p.maxDuration > 0 {
-		ctx, cancel = context.WithTimeout(ctx, p.maxDuration)
+		ctx, cancel = withClockTimeout(ctx, clock, p.maxDuration)
 	} else {
 		ctx, cancel = context.WithCancel(ctx)
 	}
 
+	steps := make([]Step, 0, p.steps.Len())
+	for s := p.steps.Front(); s != nil; s = s.Next() {
+		steps = append(steps, Wrap(s.Value.(Step), p.middlewares...))
+	}
+	selected, err := p.selectedSteps(steps)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	progress := newProgressTracker(p.progressFunc, clock, steps)
+	p.resetResults(steps)
+
+	var (
+		resumed   int
+		completed = make(map[int]bool)
+	)
+	if resume != nil {
+		p.state.Restore(resume.State)
+		resumed = resume.Next
+		for _, c := range resume.Completed {
+			completed[c.Index] = true
+		}
+	}
+
 	errCh := make(chan error)
 	go func(cancelFunc context.CancelFunc) {
 		var (
-			lastOK *list.Element
+			lastOK = -1
 			err    error
+
+			// executed tracks the steps to clean up during the cleanup phase.
+			executed = make([]bool, len(steps))
 		)
 
 		defer cancelFunc()
 
-		for s := p.steps.Front(); s != nil; s = s.Next() {
-			step := s.Value.(Step)
+		p.emitPlan(EventPlanStart, nil)
 
-			for attempt := 0; attempt <= step.Retries(); attempt++ {
+		for i, step := range steps {
+			// Skip the steps preceding the checkpoint the execution is
+			// resumed from, only the ones completed are to be cleaned up.
+			if i < resumed {
+				p.emit(Event{Type: EventStepSkip, Step: i, StepName: NameOf(step)})
+				progress.stepDone(i)
+				if completed[i] {
+					executed[i], lastOK = true, i
+				}
+				continue
+			}
+
+			if !selected[i] {
+				p.emit(Event{Type: EventStepSkip, Step: i, StepName: NameOf(step)})
+				progress.stepDone(i)
+				continue
+			}
+
+			// Pause or abort the execution at the step boundary if requested.
+			if err = run.boundary(ctx); err != nil {
+				goto stop
+			}
+
+			stepCtx := progress.context(ctx, i)
+
+			progress.report(i, 0)
+			p.emit(Event{Type: EventStepStart, Step: i, StepName: NameOf(step), Attempt: 1})
+
+			attempt := 0
+			for ; attempt <= step.Retries(); attempt++ {
 				if err = ctx.Err(); err != nil {
+					p.emitPlan(EventPlanEnd, err)
 					errCh <- err
 					return
 				}
 
-				if err = step.PreExec(ctx, p.state); err != nil {
+				if err = p.execPhase(stepCtx, i, step, attempt, PhasePreExec, step.PreExec); err != nil {
 					if !p.continueOnError {
+						p.emitStepEnd(i, step, attempt, err)
 						goto stop
 					}
 					if step.Retries() > attempt {
+						p.emitStepRetry(i, step, attempt, err)
 						continue
 					}
 				}
 
-				if err = step.Exec(ctx, p.state); err != nil {
+				if err = p.execPhase(stepCtx, i, step, attempt, PhaseExec, step.Exec); err != nil {
 					if !p.continueOnError {
+						p.emitStepEnd(i, step, attempt, err)
 						goto stop
 					}
 					if step.Retries() > attempt {
+						p.emitStepRetry(i, step, attempt, err)
 						continue
 					}
 				}
 
-				if err = step.PostExec(ctx, p.state); err != nil {
+				if err = p.execPhase(stepCtx, i, step, attempt, PhasePostExec, step.PostExec); err != nil {
 					if !p.continueOnError {
+						p.emitStepEnd(i, step, attempt, err)
 						goto stop
 					}
 					if step.Retries() > attempt {
+						p.emitStepRetry(i, step, attempt, err)
 						continue
 					}
 				}
+
+				// The step executed successfully, no more attempts needed.
+				if err == nil {
+					break
+				}
+			}
+			if attempt > step.Retries() {
+				attempt = step.Retries()
 			}
 
+			progress.stepDone(i)
+			p.emitStepEnd(i, step, attempt, err)
+
 			// Save last successful step as starting point of the cleanup phase.
-			lastOK = s
+			executed[i], lastOK = true, i
+
+			if store != nil {
+				if cerr := p.saveCheckpoint(ctx, store, steps, executed, i+1); cerr != nil {
+					if err == nil {
+						err = cerr
+					}
+					goto stop
+				}
+			}
 		}
 	stop:
+		cleanupCtx := context.WithValue(ctx, planErrCtxKey{}, err)
 
-		for step := lastOK; step != nil; step = step.Prev() {
+		for i := lastOK; i >= 0; i-- {
 			if ctx.Err() != nil {
+				p.emitPlan(EventPlanEnd, ctx.Err())
 				errCh <- ctx.Err()
 				return
 			}
 
-			// Skip pause steps during cleanup phase.
-			if _, ok := step.Value.(*pauseStep); ok {
+			// Skip pause steps and steps not executed during cleanup phase.
+			if !executed[i] || isPauseStep(steps[i]) {
 				continue
 			}
 
-			step.Value.(Step).Cleanup(ctx, p.state)
+			_ = p.execPhase(cleanupCtx, i, steps[i], 0, PhaseCleanup,
+				func(ctx context.Context, state *State) error {
+					steps[i].Cleanup(ctx, state)
+					return nil
+				})
 		}
 
+		// The plan execution is over, there is nothing left to resume.
+		if store != nil {
+			if cerr := store.Clear(ctx); cerr != nil && err == nil {
+				err = fmt.Errorf("unable to clear checkpoint: %w", cerr)
+			}
+		}
+
+		p.emitPlan(EventPlanEnd, err)
 		errCh <- err
 	}(cancel)
 
@@ -167,6 +318,17 @@ func (p *Plan) Execute(ctx context.Context) error {
 	return nil
 }
 
+type planErrCtxKey struct{}
+
+// PlanError returns the error the plan execution stopped with, when called
+// from a step's Cleanup hook with the context it has been passed: nil
+// indicates that the plan executed successfully, allowing steps to tell a
+// rollback from a regular cleanup.
+func PlanError(ctx context.Context) error {
+	err, _ := ctx.Value(planErrCtxKey{}).(error)
+	return err
+}
+
 // State returns the plan's current state shared between steps.
 func (p *Plan) State() *State {
 	return p.state
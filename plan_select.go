@@ -0,0 +1,199 @@
+package gsd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStepNotFound represents an error reported when a step selection option
+// refers to a step that doesn't exist in the plan.
+var ErrStepNotFound = errors.New("step not found")
+
+// stepFilter represents a function selecting the steps to execute amongst
+// the plan's steps: it returns whether each step is selected.
+type stepFilter func(steps []Step) ([]bool, error)
+
+// PlanOptRunFrom instructs the plan to start its execution from the first
+// step named name, skipping the previous ones.
+//
+// The steps selection options (PlanOptRunFrom, PlanOptRunUntil,
+// PlanOptOnlySteps, PlanOptStepRange, PlanOptIncludeTags and
+// PlanOptExcludeTags) can be combined, in which case only the steps selected
+// by all of them are executed. Steps not selected are skipped: they are
+// neither executed nor cleaned up during the cleanup phase, which only
+// concerns the steps actually executed. Note: the plan state is not altered
+// by the skipped steps, so the selected steps must not rely on state values
+// produced by the skipped ones.
+func PlanOptRunFrom(name string) PlanOpt {
+	return func(p *Plan) error {
+		p.stepFilters = append(p.stepFilters, func(steps []Step) ([]bool, error) {
+			from, err := indexOfStep(steps, name)
+			if err != nil {
+				return nil, err
+			}
+
+			return selectSteps(steps, func(i int, _ Step) bool { return i >= from }), nil
+		})
+		return nil
+	}
+}
+
+// PlanOptRunUntil instructs the plan to stop its execution after the first
+// step named name, skipping the next ones. See PlanOptRunFrom for the steps
+// selection semantics.
+func PlanOptRunUntil(name string) PlanOpt {
+	return func(p *Plan) error {
+		p.stepFilters = append(p.stepFilters, func(steps []Step) ([]bool, error) {
+			until, err := indexOfStep(steps, name)
+			if err != nil {
+				return nil, err
+			}
+
+			return selectSteps(steps, func(i int, _ Step) bool { return i <= until }), nil
+		})
+		return nil
+	}
+}
+
+// PlanOptOnlySteps instructs the plan to only execute the steps named names,
+// skipping the other ones. See PlanOptRunFrom for the steps selection
+// semantics.
+func PlanOptOnlySteps(names ...string) PlanOpt {
+	return func(p *Plan) error {
+		p.stepFilters = append(p.stepFilters, func(steps []Step) ([]bool, error) {
+			only := make(map[string]struct{}, len(names))
+			for _, name := range names {
+				if _, err := indexOfStep(steps, name); err != nil {
+					return nil, err
+				}
+				only[name] = struct{}{}
+			}
+
+			return selectSteps(steps, func(_ int, s Step) bool {
+				_, ok := only[NameOf(s)]
+				return ok
+			}), nil
+		})
+		return nil
+	}
+}
+
+// PlanOptStepRange instructs the plan to only execute the steps whose index
+// (starting at 0, in the order they have been added to the plan) is between
+// from and to included, skipping the other ones. See PlanOptRunFrom for the
+// steps selection semantics.
+func PlanOptStepRange(from, to int) PlanOpt {
+	return func(p *Plan) error {
+		if from < 0 || to < from {
+			return fmt.Errorf("invalid step range [%d, %d]", from, to)
+		}
+
+		p.stepFilters = append(p.stepFilters, func(steps []Step) ([]bool, error) {
+			if to >= len(steps) {
+				return nil, fmt.Errorf("invalid step range [%d, %d]: plan has %d steps", from, to, len(steps))
+			}
+
+			return selectSteps(steps, func(i int, _ Step) bool { return i >= from && i <= to }), nil
+		})
+		return nil
+	}
+}
+
+// Tagged is an optional interface a Step can implement to carry arbitrary
+// tags, allowing to select the steps to execute using the PlanOptIncludeTags
+// and PlanOptExcludeTags options.
+type Tagged interface {
+	StepTags() []string
+}
+
+// TagsOf returns the tags of the step s, or nil if neither s nor the steps it
+// wraps (see Wrapper) implement the Tagged interface.
+func TagsOf(s Step) []string {
+	var tags []string
+
+	unwrapStep(s, func(s Step) bool {
+		t, ok := s.(Tagged)
+		if ok {
+			tags = t.StepTags()
+		}
+		return ok
+	})
+
+	return tags
+}
+
+// PlanOptIncludeTags instructs the plan to only execute the steps tagged with
+// at least one of the tags, skipping the other ones (including untagged
+// steps). See PlanOptRunFrom for the steps selection semantics.
+func PlanOptIncludeTags(tags ...string) PlanOpt {
+	return func(p *Plan) error {
+		p.stepFilters = append(p.stepFilters, func(steps []Step) ([]bool, error) {
+			return selectSteps(steps, func(_ int, s Step) bool { return hasAnyTag(s, tags) }), nil
+		})
+		return nil
+	}
+}
+
+// PlanOptExcludeTags instructs the plan to skip the steps tagged with at least
+// one of the tags. See PlanOptRunFrom for the steps selection semantics.
+func PlanOptExcludeTags(tags ...string) PlanOpt {
+	return func(p *Plan) error {
+		p.stepFilters = append(p.stepFilters, func(steps []Step) ([]bool, error) {
+			return selectSteps(steps, func(_ int, s Step) bool { return !hasAnyTag(s, tags) }), nil
+		})
+		return nil
+	}
+}
+
+// hasAnyTag returns whether the step s is tagged with at least one of the tags.
+func hasAnyTag(s Step, tags []string) bool {
+	for _, st := range TagsOf(s) {
+		for _, t := range tags {
+			if st == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// indexOfStep returns the index of the first step named name in steps.
+func indexOfStep(steps []Step, name string) (int, error) {
+	for i, s := range steps {
+		if NameOf(s) == name {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%w: %q", ErrStepNotFound, name)
+}
+
+// selectSteps returns whether each step of steps is selected by the function f.
+func selectSteps(steps []Step, f func(int, Step) bool) []bool {
+	selected := make([]bool, len(steps))
+	for i, s := range steps {
+		selected[i] = f(i, s)
+	}
+
+	return selected
+}
+
+// selectedSteps returns whether each step of steps is selected for execution
+// by all the plan's step filters.
+func (p *Plan) selectedSteps(steps []Step) ([]bool, error) {
+	selected := selectSteps(steps, func(int, Step) bool { return true })
+
+	for _, filter := range p.stepFilters {
+		s, err := filter(steps)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range selected {
+			selected[i] = selected[i] && s[i]
+		}
+	}
+
+	return selected, nil
+}
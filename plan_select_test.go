@@ -0,0 +1,171 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSelectPlan(t *testing.T, opts ...PlanOpt) *Plan {
+	plan, err := NewPlan(opts...)
+	require.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		name := name
+		plan.AddStep(&GenericStep{
+			Name:        name,
+			ExecFunc:    func(ctx context.Context, state *State) error { return testStepFunc(state, name) },
+			CleanupFunc: func(ctx context.Context, state *State) { _ = testStepFunc(state, "~"+name) },
+		})
+	}
+
+	return plan
+}
+
+func TestPlan_StepsSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []PlanOpt
+		expected string
+		err      error
+	}{
+		{
+			name:     "all steps",
+			expected: "abcd~d~c~b~a",
+		},
+		{
+			name:     "run from",
+			opts:     []PlanOpt{PlanOptRunFrom("b")},
+			expected: "bcd~d~c~b",
+		},
+		{
+			name:     "run until",
+			opts:     []PlanOpt{PlanOptRunUntil("c")},
+			expected: "abc~c~b~a",
+		},
+		{
+			name:     "run from and until",
+			opts:     []PlanOpt{PlanOptRunFrom("b"), PlanOptRunUntil("c")},
+			expected: "bc~c~b",
+		},
+		{
+			name:     "only steps",
+			opts:     []PlanOpt{PlanOptOnlySteps("d", "a")},
+			expected: "ad~d~a",
+		},
+		{
+			name:     "step range",
+			opts:     []PlanOpt{PlanOptStepRange(1, 2)},
+			expected: "bc~c~b",
+		},
+		{
+			name: "unknown step",
+			opts: []PlanOpt{PlanOptRunFrom("x")},
+			err:  ErrStepNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := testSelectPlan(t, tt.opts...)
+
+			err := plan.Execute(context.Background())
+			if tt.err != nil {
+				require.True(t, errors.Is(err, tt.err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, plan.State().Get("test"))
+		})
+	}
+
+	_, err := NewPlan(PlanOptStepRange(2, 1))
+	require.Error(t, err)
+
+	require.Error(t, testSelectPlan(t, PlanOptStepRange(0, 4)).Execute(context.Background()))
+}
+
+func TestPlan_StepsSelectionCleanup(t *testing.T) {
+	plan := testSelectPlan(t, PlanOptRunFrom("b"))
+	plan.AddStep(&GenericStep{
+		Name:     "e",
+		ExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+	})
+
+	require.Error(t, plan.Execute(context.Background()))
+
+	// Skipped step "a" must not be cleaned up.
+	require.Equal(t, "bcd~d~c~b", plan.State().Get("test"))
+
+	results := plan.Results()
+	require.Equal(t, StepSkipped, results[0].Status)
+	require.Equal(t, StepSucceeded, results[1].Status)
+	require.Equal(t, StepFailed, results[4].Status)
+}
+
+func TestPlan_StepsSelectionTags(t *testing.T) {
+	newPlan := func(opts ...PlanOpt) *Plan {
+		plan, err := NewPlan(opts...)
+		require.NoError(t, err)
+
+		for _, s := range []struct {
+			name string
+			tags []string
+		}{
+			{name: "a", tags: []string{"smoke"}},
+			{name: "b", tags: []string{"smoke", "destructive"}},
+			{name: "c"},
+			{name: "d", tags: []string{"destructive"}},
+		} {
+			name := s.name
+			plan.AddStep((&GenericStep{
+				Name:     name,
+				Tags:     s.tags,
+				ExecFunc: func(ctx context.Context, state *State) error { return testStepFunc(state, name) },
+			}).With(func(next Step) Step { return &WrappedStep{Step: next} }))
+		}
+
+		return plan
+	}
+
+	tests := []struct {
+		name     string
+		opts     []PlanOpt
+		expected string
+	}{
+		{
+			name:     "include",
+			opts:     []PlanOpt{PlanOptIncludeTags("smoke")},
+			expected: "ab",
+		},
+		{
+			name:     "exclude",
+			opts:     []PlanOpt{PlanOptExcludeTags("destructive")},
+			expected: "ac",
+		},
+		{
+			name:     "include and exclude",
+			opts:     []PlanOpt{PlanOptIncludeTags("smoke"), PlanOptExcludeTags("destructive")},
+			expected: "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := newPlan(tt.opts...)
+			require.NoError(t, plan.Execute(context.Background()))
+			require.Equal(t, tt.expected, plan.State().Get("test"))
+
+			for _, r := range plan.Results() {
+				if strings.Contains(tt.expected, r.Name) {
+					require.Equal(t, StepSucceeded, r.Status)
+				} else {
+					require.Equal(t, StepSkipped, r.Status)
+				}
+			}
+		})
+	}
+}
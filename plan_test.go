@@ -4,7 +4,6 @@ import (
 	"container/list"
 	"context"
 	"errors"
-	"sync"
 	"testing"
 	"time"
 
@@ -42,7 +41,7 @@ func TestNewPlan(t *testing.T) {
 
 	actual, err := NewPlan(PlanOptContinueOnError())
 	expected := &Plan{
-		state:           &State{sync.Map{}},
+		state:           &State{},
 		steps:           list.New(),
 		continueOnError: true,
 	}
@@ -53,7 +52,7 @@ func TestNewPlan(t *testing.T) {
 
 func TestPlan_AddStep(t *testing.T) {
 	plan := &Plan{
-		state: &State{sync.Map{}},
+		state: &State{},
 		steps: list.New(),
 	}
 
@@ -191,6 +190,28 @@ func TestPlan_Execute_WithRetries(t *testing.T) {
 	require.Equal(t, "**", actual)
 }
 
+func TestPlan_Execute_WithRetries_Success(t *testing.T) {
+	plan, err := NewPlan(PlanOptContinueOnError())
+	require.NoError(t, err)
+
+	// The step must not be attempted again once successful.
+	execs := 0
+	testStep := &GenericStep{
+		ExecFunc: func(ctx context.Context, state *State) error {
+			if execs++; execs == 1 {
+				return errors.New("blah")
+			}
+			return nil
+		},
+	}
+
+	require.NoError(t, plan.
+		AddStep(testStep.WithRetries(3)).
+		Execute(context.Background()))
+	require.Equal(t, 2, execs)
+	require.Equal(t, 2, plan.Results()[0].Attempts)
+}
+
 func TestPlan_AddPause(t *testing.T) {
 	plan, err := NewPlan()
 	require.NoError(t, err)
@@ -209,9 +230,74 @@ func TestPlan_AddPause(t *testing.T) {
 		"plan execution should not take less than %s", pause)
 }
 
+func TestPlan_AddPause_WithTimeout(t *testing.T) {
+	plan, err := NewPlan(PlanOptLimitDuration(100 * time.Millisecond))
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.EqualError(t, plan.AddPause(time.Hour).Execute(context.Background()), ErrTimeout.Error())
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestPlan_AddWaitUntil(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	polls := 0
+	require.NoError(t, plan.
+		AddWaitUntil(func(ctx context.Context, state *State) (bool, error) {
+			polls++
+			return polls == 3, nil
+		}, time.Millisecond, time.Second).
+		Execute(context.Background()))
+	require.Equal(t, 3, polls)
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	require.Equal(t, ErrWaitTimeout, plan.
+		AddWaitUntil(func(ctx context.Context, state *State) (bool, error) { return false, nil },
+			time.Millisecond, 10*time.Millisecond).
+		Execute(context.Background()))
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	require.EqualError(t, plan.
+		AddWaitUntil(func(ctx context.Context, state *State) (bool, error) { return false, errors.New("blah") },
+			time.Millisecond, 0).
+		Execute(context.Background()), "blah")
+}
+
 func TestPlan_State(t *testing.T) {
 	plan, err := NewPlan()
 
 	require.NoError(t, err)
 	require.Equal(t, plan.state, plan.State())
 }
+
+func TestPlanError(t *testing.T) {
+	var cleanupErrs []error
+
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	plan.AddStep(&GenericStep{
+		CleanupFunc: func(ctx context.Context, state *State) { cleanupErrs = append(cleanupErrs, PlanError(ctx)) },
+		ExecFunc: func(ctx context.Context, state *State) error {
+			require.NoError(t, PlanError(ctx))
+			return nil
+		},
+	})
+
+	require.NoError(t, plan.Execute(context.Background()))
+
+	plan.AddStep(&GenericStep{
+		ExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+	})
+	require.EqualError(t, plan.Execute(context.Background()), "blah")
+
+	require.Len(t, cleanupErrs, 2)
+	require.NoError(t, cleanupErrs[0])
+	require.EqualError(t, cleanupErrs[1], "blah")
+}
@@ -0,0 +1,140 @@
+package gsd
+
+import (
+	"context"
+	"time"
+)
+
+// Progress represents a snapshot of a plan execution progress, reported to
+// the function registered using the PlanOptProgress option.
+type Progress struct {
+	// Step is the index (starting at 1) of the step currently executed.
+	Step int
+
+	// Total is the total number of steps in the plan, pauses included.
+	Total int
+
+	// StepProgress is the completion ratio (between 0 and 1) of the current
+	// step, as reported by the step itself using the ReportProgress function.
+	StepProgress float64
+
+	// Elapsed is the duration elapsed since the beginning of the plan
+	// execution.
+	Elapsed time.Duration
+
+	// Remaining is the estimated remaining duration until the completion of
+	// the plan execution, or 0 if it cannot be estimated yet.
+	Remaining time.Duration
+}
+
+// ProgressFunc represents a function receiving plan execution progress
+// reports.
+type ProgressFunc func(Progress)
+
+// PlanOptProgress instructs the plan to report its execution progress to the
+// function f. The function is called synchronously by the plan executor
+// before and after each step, and every time a step reports sub-step
+// progress: it should return quickly.
+func PlanOptProgress(f ProgressFunc) PlanOpt {
+	return func(p *Plan) error {
+		p.progressFunc = f
+		return nil
+	}
+}
+
+// Weighted is an optional interface a Step can implement to indicate its
+// relative weight compared to the other steps of the plan, used to estimate
+// the plan's remaining execution time. Steps not implementing this interface,
+// or returning a non-positive weight, have a weight of 1.
+type Weighted interface {
+	Weight() float64
+}
+
+type progressCtxKey struct{}
+
+// ReportProgress reports the completion ratio pct (between 0 and 1) of the
+// step being currently executed. It is intended to be called from a step's
+// hooks with the context they have been passed, and is a no-op if the plan
+// has no progress function registered.
+func ReportProgress(ctx context.Context, pct float64) {
+	if f, ok := ctx.Value(progressCtxKey{}).(func(float64)); ok {
+		f(pct)
+	}
+}
+
+// progressTracker computes plan execution progress reports.
+type progressTracker struct {
+	f       ProgressFunc
+	clock   Clock
+	start   time.Time
+	weights []float64
+	total   float64
+	done    float64
+}
+
+func newProgressTracker(f ProgressFunc, clock Clock, steps []Step) *progressTracker {
+	t := progressTracker{
+		f:       f,
+		clock:   clock,
+		start:   clock.Now(),
+		weights: make([]float64, len(steps)),
+	}
+
+	for i, step := range steps {
+		t.weights[i] = 1
+		unwrapStep(step, func(s Step) bool {
+			w, ok := s.(Weighted)
+			if ok && w.Weight() > 0 {
+				t.weights[i] = w.Weight()
+			}
+			return ok
+		})
+		t.total += t.weights[i]
+	}
+
+	return &t
+}
+
+// report reports the progress of the step at index i (starting at 0).
+func (t *progressTracker) report(i int, pct float64) {
+	if t.f == nil {
+		return
+	}
+
+	if pct < 0 {
+		pct = 0
+	} else if pct > 1 {
+		pct = 1
+	}
+
+	progress := Progress{
+		Step:         i + 1,
+		Total:        len(t.weights),
+		StepProgress: pct,
+		Elapsed:      t.clock.Now().Sub(t.start),
+	}
+
+	// The remaining duration is extrapolated from the average duration per
+	// unit of weight observed so far.
+	if done := t.done + t.weights[i]*pct; done > 0 {
+		progress.Remaining = time.Duration(float64(progress.Elapsed) / done * (t.total - done))
+	}
+
+	t.f(progress)
+}
+
+// stepDone marks the step at index i (starting at 0) as completed.
+func (t *progressTracker) stepDone(i int) {
+	t.report(i, 1)
+	t.done += t.weights[i]
+}
+
+// context returns a context allowing the step at index i (starting at 0) to
+// report its progress using the ReportProgress function.
+func (t *progressTracker) context(ctx context.Context, i int) context.Context {
+	if t.f == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, progressCtxKey{}, func(pct float64) { t.report(i, pct) })
+}
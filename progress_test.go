@@ -0,0 +1,61 @@
+package gsd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanOptProgress(t *testing.T) {
+	plan := &Plan{}
+	f := func(Progress) {}
+
+	require.NoError(t, PlanOptProgress(f)(plan))
+	require.NotNil(t, plan.progressFunc)
+}
+
+func TestPlan_Execute_WithProgress(t *testing.T) {
+	var reports []Progress
+
+	plan, err := NewPlan(PlanOptProgress(func(p Progress) { reports = append(reports, p) }))
+	require.NoError(t, err)
+
+	require.NoError(t, plan.
+		AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error {
+				time.Sleep(10 * time.Millisecond)
+				ReportProgress(ctx, 0.5)
+				return nil
+			},
+		}).
+		AddStep((&GenericStep{}).WithWeight(3)).
+		Execute(context.Background()))
+
+	require.Len(t, reports, 5)
+
+	require.Equal(t, 1, reports[0].Step)
+	require.Equal(t, 2, reports[0].Total)
+	require.Zero(t, reports[0].StepProgress)
+	require.Zero(t, reports[0].Remaining)
+
+	require.Equal(t, 1, reports[1].Step)
+	require.Equal(t, 0.5, reports[1].StepProgress)
+	require.NotZero(t, reports[1].Remaining)
+
+	require.Equal(t, 2, reports[4].Step)
+	require.Equal(t, float64(1), reports[4].StepProgress)
+	require.Zero(t, reports[4].Remaining)
+}
+
+func TestReportProgress(t *testing.T) {
+	var actual float64
+
+	ctx := context.WithValue(context.Background(), progressCtxKey{}, func(pct float64) { actual = pct })
+	ReportProgress(ctx, 0.42)
+	require.Equal(t, 0.42, actual)
+
+	// Must not panic without a registered progress function.
+	ReportProgress(context.Background(), 0.42)
+}
@@ -0,0 +1,279 @@
+// Package render provides human-friendly renderers of gsd plans executions.
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falzm/gsd"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiFaint = "\x1b[2m"
+
+	// ansiClearLine erases the current line.
+	ansiClearLine = "\x1b[2K"
+
+	// ansiLinesUp moves the cursor to the beginning of the n-th previous line.
+	ansiLinesUp = "\x1b[%dF"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinnerInterval is the refresh interval of the live rendering.
+const spinnerInterval = 100 * time.Millisecond
+
+type stepStatus int
+
+const (
+	stepPending stepStatus = iota
+	stepRunning
+	stepSucceeded
+	stepFailed
+	stepSkipped
+)
+
+type stepRow struct {
+	name    string
+	status  stepStatus
+	retries int
+	start   time.Time
+	end     time.Time
+	err     error
+}
+
+func (r *stepRow) duration(now time.Time) time.Duration {
+	if r.end.IsZero() {
+		return now.Sub(r.start).Truncate(10 * time.Millisecond)
+	}
+
+	return r.end.Sub(r.start).Truncate(10 * time.Millisecond)
+}
+
+// Terminal is a plan execution renderer drawing the plan's progress as a
+// live checklist of steps when writing to a terminal, or as plain lines of
+// text otherwise (e.g. when the output is redirected to a file or CI logs).
+// It is intended to be registered on a plan using the gsd.PlanOptEventHandler
+// option:
+//
+//	r := render.NewTerminal(os.Stderr)
+//	plan, err := gsd.NewPlan(gsd.PlanOptEventHandler(r.Handle))
+type Terminal struct {
+	mu    sync.Mutex
+	w     io.Writer
+	live  bool
+	steps []*stepRow
+	drawn int
+	frame int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewTerminal returns a new terminal renderer writing to w. The live
+// rendering is enabled if w is a terminal.
+func NewTerminal(w io.Writer) *Terminal {
+	return &Terminal{
+		w:    w,
+		live: isTerminal(w),
+	}
+}
+
+// isTerminal returns true if w is a terminal device supporting ANSI escape
+// sequences.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	if term := os.Getenv("TERM"); term == "" || term == "dumb" {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Handle handles the plan execution event e.
+func (t *Terminal) Handle(e gsd.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch e.Type {
+	case gsd.EventPlanStart:
+		t.steps = make([]*stepRow, e.Total)
+		for i := range t.steps {
+			t.steps[i] = &stepRow{name: fmt.Sprintf("step %d", i+1)}
+		}
+		t.drawn = 0
+
+		if t.live {
+			t.stop, t.done = make(chan struct{}), make(chan struct{})
+			go t.animate(t.stop, t.done)
+		}
+
+	case gsd.EventStepStart:
+		row := t.row(e)
+		row.status, row.start = stepRunning, e.Time
+
+		t.println(e, "started")
+
+	case gsd.EventStepSkip:
+		t.row(e).status = stepSkipped
+
+		t.println(e, "skipped")
+
+	case gsd.EventStepRetry:
+		row := t.row(e)
+		row.retries++
+
+		t.println(e, fmt.Sprintf("attempt %d failed: %s, retrying", e.Attempt, e.Err))
+
+	case gsd.EventStepEnd:
+		row := t.row(e)
+		row.end, row.err = e.Time, e.Err
+
+		if e.Err != nil {
+			row.status = stepFailed
+			t.println(e, fmt.Sprintf("failed after %s: %s", row.duration(e.Time), e.Err))
+		} else {
+			row.status = stepSucceeded
+			t.println(e, fmt.Sprintf("done in %s", row.duration(e.Time)))
+		}
+
+	case gsd.EventPhaseStart:
+		if e.Phase == gsd.PhaseCleanup {
+			t.println(e, "cleaning up")
+		}
+
+	case gsd.EventPlanEnd:
+		if t.live && t.stop != nil {
+			close(t.stop)
+			t.mu.Unlock()
+			<-t.done
+			t.mu.Lock()
+			t.stop = nil
+			t.draw()
+			return
+		}
+
+		if e.Err != nil {
+			fmt.Fprintf(t.w, "plan failed: %s\n", e.Err)
+		} else {
+			fmt.Fprintln(t.w, "plan completed")
+		}
+	}
+
+	if t.live {
+		t.draw()
+	}
+}
+
+// row returns the checklist row corresponding to the step of event e.
+func (t *Terminal) row(e gsd.Event) *stepRow {
+	// Tolerate events received without a preceding EventPlanStart event.
+	for len(t.steps) <= e.Step {
+		t.steps = append(t.steps, &stepRow{name: fmt.Sprintf("step %d", len(t.steps)+1)})
+	}
+
+	row := t.steps[e.Step]
+	if e.StepName != "" {
+		row.name = e.StepName
+	}
+
+	return row
+}
+
+// println writes a plain text line about the step of event e, unless the
+// live rendering is enabled.
+func (t *Terminal) println(e gsd.Event, msg string) {
+	if t.live {
+		return
+	}
+
+	fmt.Fprintf(t.w, "[%d/%d] %s: %s\n", e.Step+1, len(t.steps), t.row(e).name, msg)
+}
+
+// animate periodically redraws the checklist until the stop channel is
+// closed, then closes the done channel.
+func (t *Terminal) animate(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			t.mu.Lock()
+			t.frame++
+			t.draw()
+			t.mu.Unlock()
+		}
+	}
+}
+
+// draw (re)draws the steps checklist.
+func (t *Terminal) draw() {
+	var (
+		b   strings.Builder
+		now = time.Now()
+	)
+
+	if t.drawn > 0 {
+		fmt.Fprintf(&b, ansiLinesUp, t.drawn)
+	}
+
+	for _, row := range t.steps {
+		b.WriteString(ansiClearLine)
+
+		switch row.status {
+		case stepPending:
+			b.WriteString(ansiFaint + "○ " + row.name + ansiReset)
+
+		case stepRunning:
+			b.WriteString(spinnerFrames[t.frame%len(spinnerFrames)] + " " + row.name)
+
+		case stepSucceeded:
+			b.WriteString(ansiGreen + "✓" + ansiReset + " " + row.name)
+
+		case stepFailed:
+			b.WriteString(ansiRed + "✗ " + row.name + ansiReset)
+
+		case stepSkipped:
+			b.WriteString(ansiFaint + "- " + row.name + " (skipped)" + ansiReset)
+		}
+
+		if row.retries > 0 {
+			fmt.Fprintf(&b, " (retries: %d)", row.retries)
+		}
+
+		if row.status != stepPending && row.status != stepSkipped {
+			fmt.Fprintf(&b, " %s%s%s", ansiFaint, row.duration(now), ansiReset)
+		}
+
+		if row.err != nil {
+			fmt.Fprintf(&b, ": %s%s%s", ansiRed, row.err, ansiReset)
+		}
+
+		b.WriteString("\n")
+	}
+
+	t.drawn = len(t.steps)
+
+	_, _ = io.WriteString(t.w, b.String())
+}
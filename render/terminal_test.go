@@ -0,0 +1,57 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falzm/gsd"
+)
+
+func TestTerminal_Handle_Plain(t *testing.T) {
+	var out bytes.Buffer
+
+	r := NewTerminal(&out)
+	require.False(t, r.live)
+
+	plan, err := gsd.NewPlan(gsd.PlanOptEventHandler(r.Handle))
+	require.NoError(t, err)
+
+	require.Error(t, plan.
+		AddStep(&gsd.GenericStep{Name: "build"}).
+		AddStep(&gsd.GenericStep{
+			Name:     "deploy",
+			ExecFunc: func(ctx context.Context, state *gsd.State) error { return errors.New("blah") },
+		}).
+		Execute(context.Background()))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 6)
+	require.Equal(t, "[1/2] build: started", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "[1/2] build: done in "))
+	require.Equal(t, "[2/2] deploy: started", lines[2])
+	require.True(t, strings.HasPrefix(lines[3], "[2/2] deploy: failed after "))
+	require.True(t, strings.HasSuffix(lines[3], ": blah"))
+	require.Equal(t, "[1/2] build: cleaning up", lines[4])
+	require.Equal(t, "plan failed: blah", lines[5])
+}
+
+func TestTerminal_Handle_Live(t *testing.T) {
+	var out bytes.Buffer
+
+	r := NewTerminal(&out)
+	r.live = true
+
+	plan, err := gsd.NewPlan(gsd.PlanOptEventHandler(r.Handle))
+	require.NoError(t, err)
+
+	require.NoError(t, plan.
+		AddStep(&gsd.GenericStep{Name: "build"}).
+		Execute(context.Background()))
+
+	require.Contains(t, out.String(), ansiGreen+"✓"+ansiReset+" build")
+}
@@ -0,0 +1,122 @@
+package gsd
+
+import (
+	"fmt"
+	"time"
+)
+
+// StepStatus represents the outcome of a step execution.
+type StepStatus int
+
+const (
+	// StepNotRun indicates that the step has not been executed.
+	StepNotRun StepStatus = iota
+
+	// StepRunning indicates that the step is being executed.
+	StepRunning
+
+	// StepSucceeded indicates that the step has been executed successfully.
+	StepSucceeded
+
+	// StepFailed indicates that the step execution failed.
+	StepFailed
+
+	// StepSkipped indicates that the step has been skipped.
+	StepSkipped
+)
+
+func (s StepStatus) String() string {
+	switch s {
+	case StepNotRun:
+		return "not-run"
+	case StepRunning:
+		return "running"
+	case StepSucceeded:
+		return "succeeded"
+	case StepFailed:
+		return "failed"
+	case StepSkipped:
+		return "skipped"
+	}
+
+	return fmt.Sprintf("StepStatus(%d)", int(s))
+}
+
+// StepResult represents the result of a step execution.
+type StepResult struct {
+	// Name is the name of the step, if it implements the Named interface.
+	Name string
+
+	// Status is the outcome of the step execution.
+	Status StepStatus
+
+	// Attempts is the number of times the step has been attempted.
+	Attempts int
+
+	// Duration is the duration of the step execution, retries included but
+	// cleanup excluded.
+	Duration time.Duration
+
+	// Err is the error reported by the step's last attempt (if any).
+	Err error
+}
+
+// Results returns the results of the plan's steps from its latest execution,
+// in the order the steps have been added to the plan. It returns nil if the
+// plan has never been executed.
+func (p *Plan) Results() []StepResult {
+	p.resultsMu.Lock()
+	defer p.resultsMu.Unlock()
+
+	if p.results == nil {
+		return nil
+	}
+
+	results := make([]StepResult, len(p.results))
+	copy(results, p.results)
+
+	return results
+}
+
+// resetResults initializes the plan's step results before an execution.
+func (p *Plan) resetResults(steps []Step) {
+	p.resultsMu.Lock()
+	defer p.resultsMu.Unlock()
+
+	p.results = make([]StepResult, len(steps))
+	p.resultsStart = make([]time.Time, len(steps))
+	for i, step := range steps {
+		p.results[i].Name = NameOf(step)
+	}
+}
+
+// recordResult updates the plan's step results according to the execution
+// event e.
+func (p *Plan) recordResult(e Event) {
+	p.resultsMu.Lock()
+	defer p.resultsMu.Unlock()
+
+	if e.Step < 0 || e.Step >= len(p.results) {
+		return
+	}
+
+	result := &p.results[e.Step]
+
+	switch e.Type {
+	case EventStepStart:
+		result.Status = StepRunning
+		p.resultsStart[e.Step] = e.Time
+
+	case EventStepSkip:
+		result.Status = StepSkipped
+
+	case EventStepEnd:
+		result.Status = StepSucceeded
+		if e.Err != nil {
+			result.Status = StepFailed
+		}
+		result.Attempts = e.Attempt
+		result.Duration = e.Time.Sub(p.resultsStart[e.Step])
+		result.Err = e.Err
+	}
+}
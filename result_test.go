@@ -0,0 +1,38 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan_Results(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+	require.Nil(t, plan.Results())
+
+	require.Error(t, plan.
+		AddStep(&GenericStep{Name: "a"}).
+		AddStep(&GenericStep{
+			Name:     "b",
+			ExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+		}).
+		AddStep(&GenericStep{Name: "c"}).
+		Execute(context.Background()))
+
+	results := plan.Results()
+	require.Len(t, results, 3)
+
+	require.Equal(t, "a", results[0].Name)
+	require.Equal(t, StepSucceeded, results[0].Status)
+	require.Equal(t, 1, results[0].Attempts)
+	require.NoError(t, results[0].Err)
+
+	require.Equal(t, "b", results[1].Name)
+	require.Equal(t, StepFailed, results[1].Status)
+	require.EqualError(t, results[1].Err, "blah")
+
+	require.Equal(t, StepResult{Name: "c", Status: StepNotRun}, results[2])
+}
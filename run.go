@@ -0,0 +1,194 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RunStatus represents the status of a plan execution started using the
+// Plan.Start method.
+type RunStatus int
+
+const (
+	// RunRunning indicates that the plan is being executed.
+	RunRunning RunStatus = iota
+
+	// RunPausing indicates that the plan execution will pause before the
+	// next step, once the current step is over.
+	RunPausing
+
+	// RunPaused indicates that the plan execution is paused between steps.
+	RunPaused
+
+	// RunAborting indicates that the plan execution will abort before the
+	// next step, once the current step is over.
+	RunAborting
+
+	// RunSucceeded indicates that the plan execution is over and succeeded.
+	RunSucceeded
+
+	// RunFailed indicates that the plan execution is over and failed.
+	RunFailed
+
+	// RunAborted indicates that the plan execution has been aborted.
+	RunAborted
+)
+
+func (s RunStatus) String() string {
+	switch s {
+	case RunRunning:
+		return "running"
+	case RunPausing:
+		return "pausing"
+	case RunPaused:
+		return "paused"
+	case RunAborting:
+		return "aborting"
+	case RunSucceeded:
+		return "succeeded"
+	case RunFailed:
+		return "failed"
+	case RunAborted:
+		return "aborted"
+	}
+
+	return fmt.Sprintf("RunStatus(%d)", int(s))
+}
+
+// Run represents a handle on a plan execution started using the Plan.Start
+// method, allowing to control the execution at step boundaries: a step being
+// executed is never interrupted by the Run methods.
+type Run struct {
+	mu     sync.Mutex
+	status RunStatus
+	wake   chan struct{}
+	done   chan struct{}
+	err    error
+}
+
+// Start starts the execution of the plan in the background, and returns a
+// handle allowing to control it. The plan is executed as per Plan.Execute.
+func (p *Plan) Start(ctx context.Context) *Run {
+	r := Run{
+		status: RunRunning,
+		wake:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		r.finish(p.execute(ctx, p.checkpointStore, nil, &r))
+	}()
+
+	return &r
+}
+
+// Pause requests the plan execution to pause before the next step. It has no
+// effect if the execution is not running.
+func (r *Run) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.status == RunRunning {
+		r.setStatus(RunPausing)
+	}
+}
+
+// Resume resumes a paused plan execution, or cancels a pending pause
+// request. It has no effect if the execution is not paused.
+func (r *Run) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.status == RunPausing || r.status == RunPaused {
+		r.setStatus(RunRunning)
+	}
+}
+
+// Abort requests the plan execution to abort before the next step, even if
+// paused: the steps executed are cleaned up, and the execution returns
+// ErrAborted. It has no effect if the execution is already over.
+func (r *Run) Abort() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch r.status {
+	case RunRunning, RunPausing, RunPaused:
+		r.setStatus(RunAborting)
+	}
+}
+
+// Wait waits for the plan execution to be over, and returns its error.
+func (r *Run) Wait() error {
+	<-r.done
+	return r.err
+}
+
+// Status returns the current status of the plan execution.
+func (r *Run) Status() RunStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.status
+}
+
+// setStatus sets the run's status to s and wakes up the plan executor if
+// waiting at a step boundary. Note: r.mu must be held.
+func (r *Run) setStatus(s RunStatus) {
+	r.status = s
+	close(r.wake)
+	r.wake = make(chan struct{})
+}
+
+// boundary is called by the plan executor before executing a step. It
+// blocks while the execution is paused, and returns a non-nil error if the
+// execution must stop.
+func (r *Run) boundary(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	for {
+		r.mu.Lock()
+
+		switch r.status {
+		case RunAborting:
+			r.mu.Unlock()
+			return ErrAborted
+
+		case RunPausing, RunPaused:
+			r.status = RunPaused
+			wake := r.wake
+			r.mu.Unlock()
+
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+		default:
+			r.mu.Unlock()
+			return nil
+		}
+	}
+}
+
+// finish records the plan execution error err and marks the run as over.
+func (r *Run) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case err == nil:
+		r.status = RunSucceeded
+	case errors.Is(err, ErrAborted):
+		r.status = RunAborted
+	default:
+		r.status = RunFailed
+	}
+
+	r.err = err
+	close(r.done)
+}
@@ -0,0 +1,92 @@
+package gsd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRunPlan returns a plan whose first step "a" waits for release to be
+// closed, once started has been closed.
+func testRunPlan(t *testing.T, started, release chan struct{}) *Plan {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		plan.AddStep(&GenericStep{
+			Name: name,
+			ExecFunc: func(ctx context.Context, state *State) error {
+				if name == "a" {
+					close(started)
+					<-release
+				}
+				return testStepFunc(state, name)
+			},
+			CleanupFunc: func(ctx context.Context, state *State) { _ = testStepFunc(state, "~"+name) },
+		})
+	}
+
+	return plan
+}
+
+func TestPlan_Start(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	plan := testRunPlan(t, started, release)
+
+	run := plan.Start(context.Background())
+	require.Equal(t, RunRunning, run.Status())
+
+	// The step being executed must complete before the execution pauses.
+	<-started
+	run.Pause()
+	require.Equal(t, RunPausing, run.Status())
+	close(release)
+	require.Eventually(t, func() bool { return run.Status() == RunPaused }, time.Second, time.Millisecond)
+	require.Equal(t, "a", plan.State().Get("test"))
+
+	run.Resume()
+	require.NoError(t, run.Wait())
+	require.Equal(t, RunSucceeded, run.Status())
+	require.Equal(t, "abc~c~b~a", plan.State().Get("test"))
+
+	// Control requests have no effect once the execution is over.
+	run.Abort()
+	require.Equal(t, RunSucceeded, run.Status())
+}
+
+func TestRun_Abort(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	plan := testRunPlan(t, started, release)
+
+	run := plan.Start(context.Background())
+	<-started
+	run.Pause()
+	close(release)
+	require.Eventually(t, func() bool { return run.Status() == RunPaused }, time.Second, time.Millisecond)
+
+	run.Abort()
+	require.Equal(t, ErrAborted, run.Wait())
+	require.Equal(t, RunAborted, run.Status())
+	require.Equal(t, "a~a", plan.State().Get("test"))
+}
+
+func TestRun_Cancel(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	plan := testRunPlan(t, started, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	run := plan.Start(ctx)
+	<-started
+	run.Pause()
+	close(release)
+	require.Eventually(t, func() bool { return run.Status() == RunPaused }, time.Second, time.Millisecond)
+
+	cancel()
+	require.Equal(t, ErrCancelled, run.Wait())
+	require.Equal(t, RunFailed, run.Status())
+}
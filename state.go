@@ -1,12 +1,25 @@
 package gsd
 
-import "sync"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
 
 // State represents a key/value map provided to the plan's steps for sharing
 // state between steps. It is a wrapper around a sync.Map, so it is safe to be
-// used concurrently.
+// used concurrently. The zero value is an empty state ready to use.
 type State struct {
-	sync.Map
+	m sync.Map
+
+	guardMu sync.Mutex
+	guard   *stateGuard
+}
+
+// stateGuard restricts the keys that can be written in a State.
+type stateGuard struct {
+	allowed    map[string]struct{}
+	violations map[string]struct{}
 }
 
 // Get returns the stored value corresponding to the key k, or the nil value
@@ -18,3 +31,112 @@ func (s *State) Get(k string) interface{} {
 
 	return nil
 }
+
+// Load returns the value stored for the key k, or nil if no value is
+// present. The ok result indicates whether the value was found.
+func (s *State) Load(k interface{}) (interface{}, bool) {
+	return s.m.Load(k)
+}
+
+// Range calls f sequentially for each key and value present in the state,
+// until f returns false.
+func (s *State) Range(f func(k, v interface{}) bool) {
+	s.m.Range(f)
+}
+
+// Store sets the value v for the key k.
+func (s *State) Store(k, v interface{}) {
+	if s.allowWrite(k) {
+		s.m.Store(k, v)
+	}
+}
+
+// LoadOrStore returns the existing value for the key k if present.
+// Otherwise, it stores and returns the value v. The loaded result is true if
+// the value was loaded, false if stored. If writing the key is denied (see
+// DataFlow), nothing is stored and it returns the existing value (or nil if
+// none) and true.
+func (s *State) LoadOrStore(k, v interface{}) (interface{}, bool) {
+	if !s.allowWrite(k) {
+		existing, _ := s.m.Load(k)
+		return existing, true
+	}
+
+	return s.m.LoadOrStore(k, v)
+}
+
+// LoadAndDelete deletes the value for the key k, returning the previous value
+// if any. The loaded result reports whether the key was present. If writing
+// the key is denied (see DataFlow), nothing is deleted and it returns nil and
+// false.
+func (s *State) LoadAndDelete(k interface{}) (interface{}, bool) {
+	if !s.allowWrite(k) {
+		return nil, false
+	}
+
+	return s.m.LoadAndDelete(k)
+}
+
+// Delete deletes the value for the key k.
+func (s *State) Delete(k interface{}) {
+	if s.allowWrite(k) {
+		s.m.Delete(k)
+	}
+}
+
+// restrictWrites restricts the keys that can be written in the state to the
+// keys, until unrestrictWrites is called.
+func (s *State) restrictWrites(keys []string) {
+	s.guardMu.Lock()
+	defer s.guardMu.Unlock()
+
+	s.guard = &stateGuard{
+		allowed:    make(map[string]struct{}, len(keys)),
+		violations: make(map[string]struct{}),
+	}
+
+	for _, k := range keys {
+		s.guard.allowed[k] = struct{}{}
+	}
+}
+
+// unrestrictWrites lifts the restriction set by restrictWrites, and returns
+// the sorted list of keys whose writing has been denied in the meantime.
+func (s *State) unrestrictWrites() []string {
+	s.guardMu.Lock()
+	defer s.guardMu.Unlock()
+
+	if s.guard == nil {
+		return nil
+	}
+
+	var violations []string
+	for k := range s.guard.violations {
+		violations = append(violations, k)
+	}
+	sort.Strings(violations)
+
+	s.guard = nil
+
+	return violations
+}
+
+// allowWrite returns true if the key k can be written in the state, otherwise
+// records the violation.
+func (s *State) allowWrite(k interface{}) bool {
+	s.guardMu.Lock()
+	defer s.guardMu.Unlock()
+
+	if s.guard == nil {
+		return true
+	}
+
+	key, _ := k.(string)
+	if _, ok := s.guard.allowed[key]; ok {
+		return true
+	}
+
+	s.guard.violations[fmt.Sprint(k)] = struct{}{}
+
+	return false
+}
@@ -0,0 +1,119 @@
+package gsd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrKeyNotFound represents an error reported when a State key is not found.
+var ErrKeyNotFound = errors.New("state key not found")
+
+// KeyTypeError represents an error reported when the value stored in the
+// State for a typed key doesn't have the type of the key.
+type KeyTypeError struct {
+	// Key is the name of the key.
+	Key string
+
+	// Expected is the type of the key.
+	Expected string
+
+	// Actual is the type of the value stored in the State.
+	Actual string
+}
+
+func (e *KeyTypeError) Error() string {
+	return fmt.Sprintf("state key %q: expected value of type %s, got %s", e.Key, e.Expected, e.Actual)
+}
+
+// Key represents a typed State key, providing type-safe access to the values
+// stored in a State:
+//
+//	var who = gsd.NewKey[string]("who")
+//
+//	who.Set(state, "world")
+//	fmt.Printf("Hello, %s!\n", who.MustGet(state))
+type Key[T any] struct {
+	name       string
+	def        T
+	hasDefault bool
+}
+
+// NewKey returns a new typed State key named name.
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+// NewKeyWithDefault returns a new typed State key named name, whose value
+// defaults to def when not present in the State.
+func NewKeyWithDefault[T any](name string, def T) Key[T] {
+	return Key[T]{name: name, def: def, hasDefault: true}
+}
+
+// Name returns the name of the key, i.e. the raw key used in the State.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Lookup returns the value of the key in the state s. If the key is not
+// present, it returns the key's default value if it has one, otherwise the
+// ErrKeyNotFound error. If the stored value doesn't have the type of the
+// key, it returns a *KeyTypeError error.
+func (k Key[T]) Lookup(s *State) (T, error) {
+	v, ok := s.Load(k.name)
+	if !ok {
+		if k.hasDefault {
+			return k.def, nil
+		}
+
+		var zero T
+		return zero, fmt.Errorf("%w: %q", ErrKeyNotFound, k.name)
+	}
+
+	tv, ok := v.(T)
+	if !ok {
+		var zero T
+		return zero, &KeyTypeError{
+			Key:      k.name,
+			Expected: fmt.Sprintf("%T", &zero)[1:],
+			Actual:   fmt.Sprintf("%T", v),
+		}
+	}
+
+	return tv, nil
+}
+
+// Get returns the value of the key in the state s and true, or the key's
+// default value (or the zero value of T) and false if the key is not present
+// or if the stored value doesn't have the type of the key.
+func (k Key[T]) Get(s *State) (T, bool) {
+	if v, ok := s.Load(k.name); ok {
+		if tv, ok := v.(T); ok {
+			return tv, true
+		}
+	}
+
+	return k.def, false
+}
+
+// MustGet returns the value of the key in the state s, or the key's default
+// value if the key is not present. It panics if the key is not present and
+// has no default value, or if the stored value doesn't have the type of the
+// key.
+func (k Key[T]) MustGet(s *State) T {
+	v, err := k.Lookup(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// Set stores the value v for the key in the state s.
+func (k Key[T]) Set(s *State, v T) {
+	s.Store(k.name, v)
+}
+
+// Delete deletes the key from the state s.
+func (k Key[T]) Delete(s *State) {
+	s.Delete(k.name)
+}
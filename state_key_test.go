@@ -0,0 +1,72 @@
+package gsd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	var (
+		state = &State{}
+		who   = NewKey[string]("who")
+	)
+
+	require.Equal(t, "who", who.Name())
+
+	v, ok := who.Get(state)
+	require.False(t, ok)
+	require.Equal(t, "", v)
+
+	_, err := who.Lookup(state)
+	require.True(t, errors.Is(err, ErrKeyNotFound))
+	require.Panics(t, func() { who.MustGet(state) })
+
+	who.Set(state, "world")
+	v, ok = who.Get(state)
+	require.True(t, ok)
+	require.Equal(t, "world", v)
+	require.Equal(t, "world", who.MustGet(state))
+	require.Equal(t, "world", state.Get("who"))
+
+	state.Store("who", 42)
+	_, ok = who.Get(state)
+	require.False(t, ok)
+
+	_, err = who.Lookup(state)
+	var terr *KeyTypeError
+	require.True(t, errors.As(err, &terr))
+	require.EqualError(t, err, `state key "who": expected value of type string, got int`)
+	require.PanicsWithError(t, err.Error(), func() { who.MustGet(state) })
+
+	who.Delete(state)
+	_, ok = state.Load("who")
+	require.False(t, ok)
+
+	state.Store("err", "nope")
+	_, err = NewKey[error]("err").Lookup(state)
+	require.EqualError(t, err, `state key "err": expected value of type error, got string`)
+}
+
+func TestNewKeyWithDefault(t *testing.T) {
+	var (
+		state   = &State{}
+		retries = NewKeyWithDefault("retries", 3)
+	)
+
+	v, ok := retries.Get(state)
+	require.False(t, ok)
+	require.Equal(t, 3, v)
+	require.Equal(t, 3, retries.MustGet(state))
+
+	v, err := retries.Lookup(state)
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+
+	retries.Set(state, 5)
+	require.Equal(t, 5, retries.MustGet(state))
+
+	state.Store("retries", "nope")
+	require.Panics(t, func() { retries.MustGet(state) })
+}
@@ -0,0 +1,330 @@
+package gsd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateSnapshot represents an immutable copy of a State at a point in time.
+// Note: the snapshot is a shallow copy, values of reference types (e.g.
+// pointers, maps, slices) are shared with the State they have been copied
+// from.
+type StateSnapshot struct {
+	values map[interface{}]interface{}
+}
+
+// Snapshot returns a snapshot of the current state.
+func (s *State) Snapshot() *StateSnapshot {
+	snap := StateSnapshot{values: make(map[interface{}]interface{})}
+
+	s.Range(func(k, v interface{}) bool {
+		snap.values[k] = v
+		return true
+	})
+
+	return &snap
+}
+
+// Restore replaces the content of the state with the content of the snapshot
+// snap.
+func (s *State) Restore(snap *StateSnapshot) {
+	s.Range(func(k, _ interface{}) bool {
+		s.Delete(k)
+		return true
+	})
+
+	for k, v := range snap.values {
+		s.Store(k, v)
+	}
+}
+
+// Get returns the value corresponding to the key k in the snapshot, and
+// whether the key is present.
+func (s *StateSnapshot) Get(k interface{}) (interface{}, bool) {
+	v, ok := s.values[k]
+	return v, ok
+}
+
+// Len returns the number of keys in the snapshot.
+func (s *StateSnapshot) Len() int {
+	return len(s.values)
+}
+
+// Range calls f sequentially for each key and value present in the snapshot,
+// until f returns false.
+func (s *StateSnapshot) Range(f func(k, v interface{}) bool) {
+	for k, v := range s.values {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// TypeRegistry maps the types of State values to names, allowing to encode
+// and decode State snapshots. The zero value is not usable, use
+// NewTypeRegistry to create a registry.
+type TypeRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+// DefaultTypeRegistry is the TypeRegistry used by the State snapshots
+// encoding and decoding functions when no registry is specified.
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// NewTypeRegistry returns a new type registry, in which the basic Go types
+// (bool, string, numeric types...) as well as []string,
+// map[string]string, map[string]interface{}, []interface{}, time.Time,
+// time.Duration, Approval and HTTPResponse are registered under their Go
+// name.
+func NewTypeRegistry() *TypeRegistry {
+	r := TypeRegistry{
+		byName: make(map[string]reflect.Type),
+		byType: make(map[reflect.Type]string),
+	}
+
+	for _, sample := range []interface{}{
+		false, "",
+		0, int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		[]string{}, map[string]string{}, map[string]interface{}{}, []interface{}{},
+		time.Time{}, time.Duration(0),
+		Approval{}, HTTPResponse{},
+	} {
+		_ = r.Register(reflect.TypeOf(sample).String(), sample)
+	}
+
+	return &r
+}
+
+// Register registers the type of the value sample under the name name. The
+// type must be serializable to both JSON and YAML.
+func (r *TypeRegistry) Register(name string, sample interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		return fmt.Errorf("cannot register type of nil value as %q", name)
+	}
+
+	if other, ok := r.byName[name]; ok && other != t {
+		return fmt.Errorf("type name %q already registered for type %s", name, other)
+	}
+
+	r.byName[name] = t
+	r.byType[t] = name
+
+	return nil
+}
+
+func (r *TypeRegistry) name(v interface{}) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.byType[reflect.TypeOf(v)]
+	return name, ok
+}
+
+func (r *TypeRegistry) typ(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.byName[name]
+	return t, ok
+}
+
+// UnserializableKeysError represents an error reported when encoding a
+// State snapshot containing keys that cannot be serialized, either because
+// the key is not a string or because the type of its value has not been
+// registered in the TypeRegistry used.
+type UnserializableKeysError struct {
+	// Keys are the unserializable keys.
+	Keys []string
+}
+
+func (e *UnserializableKeysError) Error() string {
+	return "unserializable state keys: " + strings.Join(e.Keys, ", ")
+}
+
+// stateSnapshotVersion is the version of the State snapshot encoding format.
+const stateSnapshotVersion = 1
+
+type encodedValue struct {
+	Type  string      `json:"type" yaml:"type"`
+	Value interface{} `json:"value" yaml:"value"`
+}
+
+type encodedSnapshot struct {
+	Version int                     `json:"version" yaml:"version"`
+	Values  map[string]encodedValue `json:"values" yaml:"values"`
+}
+
+// encode returns the encodable representation of the snapshot. If some keys
+// cannot be serialized, the other keys are still returned along with an
+// *UnserializableKeysError error.
+func (s *StateSnapshot) encode(reg *TypeRegistry) (*encodedSnapshot, error) {
+	if reg == nil {
+		reg = DefaultTypeRegistry
+	}
+
+	var (
+		enc         = encodedSnapshot{Version: stateSnapshotVersion, Values: make(map[string]encodedValue)}
+		unsupported []string
+	)
+
+	for k, v := range s.values {
+		key, ok := k.(string)
+		if !ok {
+			unsupported = append(unsupported, fmt.Sprintf("%v (%T key)", k, k))
+			continue
+		}
+
+		name, ok := reg.name(v)
+		if !ok {
+			unsupported = append(unsupported, fmt.Sprintf("%s (unregistered type %T)", key, v))
+			continue
+		}
+
+		enc.Values[key] = encodedValue{Type: name, Value: v}
+	}
+
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return &enc, &UnserializableKeysError{Keys: unsupported}
+	}
+
+	return &enc, nil
+}
+
+// EncodeJSON writes the snapshot to w in JSON format, using the type registry
+// reg (or DefaultTypeRegistry if nil) to name the values types. If some keys
+// cannot be serialized, the other keys are still written and an
+// *UnserializableKeysError error is returned.
+func (s *StateSnapshot) EncodeJSON(w io.Writer, reg *TypeRegistry) error {
+	enc, uerr := s.encode(reg)
+
+	if err := json.NewEncoder(w).Encode(enc); err != nil {
+		return err
+	}
+
+	return uerr
+}
+
+// EncodeYAML writes the snapshot to w in YAML format, using the type registry
+// reg (or DefaultTypeRegistry if nil) to name the values types. If some keys
+// cannot be serialized, the other keys are still written and an
+// *UnserializableKeysError error is returned.
+func (s *StateSnapshot) EncodeYAML(w io.Writer, reg *TypeRegistry) error {
+	enc, uerr := s.encode(reg)
+
+	e := yaml.NewEncoder(w)
+	if err := e.Encode(enc); err != nil {
+		return err
+	}
+	if err := e.Close(); err != nil {
+		return err
+	}
+
+	return uerr
+}
+
+// decodeValue returns a new value of the type registered as name in reg,
+// decoded using the function decode.
+func decodeValue(reg *TypeRegistry, key, name string, decode func(interface{}) error) (interface{}, error) {
+	t, ok := reg.typ(name)
+	if !ok {
+		return nil, fmt.Errorf("state key %q: unregistered type %q", key, name)
+	}
+
+	v := reflect.New(t)
+	if err := decode(v.Interface()); err != nil {
+		return nil, fmt.Errorf("state key %q: unable to decode %s value: %w", key, name, err)
+	}
+
+	return v.Elem().Interface(), nil
+}
+
+// DecodeStateSnapshotJSON reads a State snapshot in JSON format from r, using
+// the type registry reg (or DefaultTypeRegistry if nil) to decode the values.
+func DecodeStateSnapshotJSON(r io.Reader, reg *TypeRegistry) (*StateSnapshot, error) {
+	if reg == nil {
+		reg = DefaultTypeRegistry
+	}
+
+	var enc struct {
+		Version int `json:"version"`
+		Values  map[string]struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		} `json:"values"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&enc); err != nil {
+		return nil, fmt.Errorf("unable to decode state snapshot: %w", err)
+	}
+
+	if enc.Version != stateSnapshotVersion {
+		return nil, fmt.Errorf("unsupported state snapshot version %d", enc.Version)
+	}
+
+	snap := StateSnapshot{values: make(map[interface{}]interface{}, len(enc.Values))}
+
+	for k, ev := range enc.Values {
+		raw := ev.Value
+		v, err := decodeValue(reg, k, ev.Type, func(v interface{}) error { return json.Unmarshal(raw, v) })
+		if err != nil {
+			return nil, err
+		}
+		snap.values[k] = v
+	}
+
+	return &snap, nil
+}
+
+// DecodeStateSnapshotYAML reads a State snapshot in YAML format from r, using
+// the type registry reg (or DefaultTypeRegistry if nil) to decode the values.
+func DecodeStateSnapshotYAML(r io.Reader, reg *TypeRegistry) (*StateSnapshot, error) {
+	if reg == nil {
+		reg = DefaultTypeRegistry
+	}
+
+	var enc struct {
+		Version int `yaml:"version"`
+		Values  map[string]struct {
+			Type  string    `yaml:"type"`
+			Value yaml.Node `yaml:"value"`
+		} `yaml:"values"`
+	}
+
+	if err := yaml.NewDecoder(r).Decode(&enc); err != nil {
+		return nil, fmt.Errorf("unable to decode state snapshot: %w", err)
+	}
+
+	if enc.Version != stateSnapshotVersion {
+		return nil, fmt.Errorf("unsupported state snapshot version %d", enc.Version)
+	}
+
+	snap := StateSnapshot{values: make(map[interface{}]interface{}, len(enc.Values))}
+
+	for k, ev := range enc.Values {
+		node := ev.Value
+		v, err := decodeValue(reg, k, ev.Type, node.Decode)
+		if err != nil {
+			return nil, err
+		}
+		snap.values[k] = v
+	}
+
+	return &snap, nil
+}
@@ -0,0 +1,104 @@
+package gsd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testStateValue struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func TestState_Snapshot(t *testing.T) {
+	state := &State{}
+	state.Store("a", 1)
+
+	snap := state.Snapshot()
+	state.Store("a", 2)
+	state.Store("b", 2)
+
+	require.Equal(t, 1, snap.Len())
+	v, ok := snap.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+
+	state.Restore(snap)
+	require.Equal(t, 1, state.Get("a"))
+	require.Nil(t, state.Get("b"))
+}
+
+func TestTypeRegistry_Register(t *testing.T) {
+	reg := NewTypeRegistry()
+
+	require.NoError(t, reg.Register("test", testStateValue{}))
+	require.NoError(t, reg.Register("test", testStateValue{}))
+	require.Error(t, reg.Register("test", &testStateValue{}))
+	require.Error(t, reg.Register("nil", nil))
+}
+
+func TestStateSnapshot_Encode(t *testing.T) {
+	reg := NewTypeRegistry()
+	require.NoError(t, reg.Register("test", testStateValue{}))
+
+	state := &State{}
+	state.Store("string", "blah")
+	state.Store("int", 42)
+	state.Store("duration", time.Minute)
+	state.Store("time", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	state.Store("strings", []string{"a", "b"})
+	state.Store("custom", testStateValue{Name: "a", Count: 1})
+	snap := state.Snapshot()
+
+	for _, format := range []struct {
+		name   string
+		encode func(*bytes.Buffer, *TypeRegistry) error
+		decode func(*bytes.Buffer, *TypeRegistry) (*StateSnapshot, error)
+	}{
+		{
+			name:   "json",
+			encode: func(b *bytes.Buffer, r *TypeRegistry) error { return snap.EncodeJSON(b, r) },
+			decode: func(b *bytes.Buffer, r *TypeRegistry) (*StateSnapshot, error) { return DecodeStateSnapshotJSON(b, r) },
+		},
+		{
+			name:   "yaml",
+			encode: func(b *bytes.Buffer, r *TypeRegistry) error { return snap.EncodeYAML(b, r) },
+			decode: func(b *bytes.Buffer, r *TypeRegistry) (*StateSnapshot, error) { return DecodeStateSnapshotYAML(b, r) },
+		},
+	} {
+		t.Run(format.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			require.NoError(t, format.encode(&buf, reg))
+
+			actual, err := format.decode(bytes.NewBuffer(buf.Bytes()), reg)
+			require.NoError(t, err)
+			require.Equal(t, snap, actual)
+
+			_, err = format.decode(bytes.NewBuffer(buf.Bytes()), nil)
+			require.EqualError(t, err, `state key "custom": unregistered type "test"`)
+
+			buf.Reset()
+			err = format.encode(&buf, nil)
+			var uerr *UnserializableKeysError
+			require.True(t, errors.As(err, &uerr))
+			require.Equal(t, []string{"custom (unregistered type gsd.testStateValue)"}, uerr.Keys)
+
+			// Serializable keys must still be encoded.
+			actual, err = format.decode(&buf, nil)
+			require.NoError(t, err)
+			require.Equal(t, snap.Len()-1, actual.Len())
+		})
+	}
+}
+
+func TestDecodeStateSnapshotYAML_Malformed(t *testing.T) {
+	// Used to crash the YAML parser (CVE-2022-28948).
+	_, err := DecodeStateSnapshotYAML(strings.NewReader("0: [:!00 \xef"), nil)
+	require.Error(t, err)
+}
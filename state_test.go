@@ -13,3 +13,32 @@ func TestState_Get(t *testing.T) {
 	require.Equal(t, "blah", state.Get("test"))
 	require.Nil(t, state.Get("lolnope"))
 }
+
+func TestState_restrictWrites(t *testing.T) {
+	state := State{}
+	state.Store("a", 1)
+
+	state.restrictWrites([]string{"b"})
+	state.Store("a", 2)
+	state.Store("b", 2)
+	state.Delete("a")
+	v, loaded := state.LoadAndDelete("a")
+	require.Nil(t, v)
+	require.False(t, loaded)
+	v, loaded = state.LoadOrStore("a", 2)
+	require.Equal(t, 1, v)
+	require.True(t, loaded)
+	v, loaded = state.LoadOrStore("c", 2)
+	require.Nil(t, v)
+	require.True(t, loaded)
+	state.Store(42, 2)
+	require.Equal(t, []string{"42", "a", "c"}, state.unrestrictWrites())
+
+	require.Equal(t, 1, state.Get("a"))
+	require.Equal(t, 2, state.Get("b"))
+	require.Nil(t, state.Get("c"))
+	require.Nil(t, state.unrestrictWrites())
+
+	state.Store("a", 3)
+	require.Equal(t, 3, state.Get("a"))
+}
@@ -0,0 +1,176 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrApprovalDenied represents an error reported if an approval requested
+// by an ApprovalStep has been denied.
+var ErrApprovalDenied = errors.New("approval denied")
+
+// ErrApprovalTimeout represents an error reported if an approval requested
+// by an ApprovalStep hasn't been granted nor denied before the step timeout.
+var ErrApprovalTimeout = errors.New("approval timeout exceeded")
+
+// DefaultApprovalStateKey is the State key under which an ApprovalStep
+// records the approval decision, unless specified otherwise.
+const DefaultApprovalStateKey = "approval"
+
+// Approval represents an approval decision.
+type Approval struct {
+	// Approved indicates whether the approval has been granted or denied.
+	Approved bool `json:"approved" yaml:"approved"`
+
+	// Approver is the identity of the person who made the decision.
+	Approver string `json:"approver" yaml:"approver"`
+
+	// Comment is an optional comment provided by the approver.
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// ApprovalRequest represents an approval request sent by an ApprovalStep to
+// an Approver.
+type ApprovalRequest struct {
+	// Step is the name of the approval step.
+	Step string `json:"step"`
+
+	// Message is the message describing what is to be approved.
+	Message string `json:"message"`
+}
+
+// Approver represents a source of approval decisions.
+type Approver interface {
+	// RequestApproval requests an approval decision, blocking until a
+	// decision is made or the context ctx is done.
+	RequestApproval(ctx context.Context, req ApprovalRequest) (Approval, error)
+}
+
+// ApprovalStep is a Step implementation blocking the plan execution until a
+// human grants an approval, e.g. before a destructive step. A denied approval
+// makes the step fail, leading to the plan cleanup phase like any other step
+// failure. The approval decision is recorded in the plan state as an
+// Approval value.
+type ApprovalStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Message describes what is to be approved.
+	Message string `yaml:"message"`
+
+	// Templated indicates whether Message is a template rendered with the
+	// plan state values (see RenderTemplate).
+	Templated bool `yaml:"templated"`
+
+	// Approver is the source of the approval decision.
+	Approver Approver `yaml:"-"`
+
+	// Timeout is the maximum duration to wait for a decision, after which the
+	// step fails with ErrApprovalTimeout. If zero, the step waits until the
+	// plan execution context is done.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// StateKey is the State key under which the approval decision is
+	// recorded. If empty, DefaultApprovalStateKey is used.
+	StateKey string `yaml:"state_key"`
+}
+
+func (s *ApprovalStep) PreExec(_ context.Context, _ *State) error {
+	if s.Approver == nil {
+		return errors.New("approval step has no approver")
+	}
+
+	return nil
+}
+
+func (s *ApprovalStep) Exec(ctx context.Context, state *State) error {
+	type decision struct {
+		approval Approval
+		err      error
+	}
+
+	var (
+		r       = newTemplateRenderer(state, s.Templated)
+		message = r.render("message", s.Message)
+	)
+	if r.err != nil {
+		return r.err
+	}
+
+	var timeout <-chan time.Time
+
+	if s.Timeout > 0 {
+		timer := ContextClock(ctx).NewTimer(s.Timeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	approverCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	decisionCh := make(chan decision, 1)
+	go func() {
+		approval, err := s.Approver.RequestApproval(approverCtx, ApprovalRequest{Step: s.StepName(), Message: message})
+		decisionCh <- decision{approval: approval, err: err}
+	}()
+
+	select {
+	case d := <-decisionCh:
+		if d.err != nil {
+			return fmt.Errorf("unable to get approval: %w", d.err)
+		}
+
+		state.Store(s.stateKey(), d.approval)
+
+		if !d.approval.Approved {
+			if d.approval.Comment != "" {
+				return fmt.Errorf("%w by %s: %s", ErrApprovalDenied, d.approval.Approver, d.approval.Comment)
+			}
+			return fmt.Errorf("%w by %s", ErrApprovalDenied, d.approval.Approver)
+		}
+
+		return nil
+
+	case <-timeout:
+		return ErrApprovalTimeout
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ApprovalStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *ApprovalStep) Cleanup(_ context.Context, _ *State) {}
+
+func (s *ApprovalStep) DryRun(_ context.Context, _ *State) (string, error) {
+	if s.Timeout > 0 {
+		return fmt.Sprintf("wait for approval %q for up to %s", s.Message, s.Timeout), nil
+	}
+
+	return fmt.Sprintf("wait for approval %q", s.Message), nil
+}
+
+func (s *ApprovalStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "approval"
+}
+
+func (s *ApprovalStep) Retries() int {
+	return 0
+}
+
+func (s *ApprovalStep) stateKey() string {
+	if s.StateKey != "" {
+		return s.StateKey
+	}
+
+	return DefaultApprovalStateKey
+}
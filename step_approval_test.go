@@ -0,0 +1,127 @@
+package gsd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testApprovalPlan(t *testing.T, step *ApprovalStep) *Plan {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	return plan.
+		AddStep(&GenericStep{
+			ExecFunc:    func(ctx context.Context, state *State) error { return testStepFunc(state, "a") },
+			CleanupFunc: func(ctx context.Context, state *State) { _ = testStepFunc(state, "~a") },
+		}).
+		AddStep(step).
+		AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error { return testStepFunc(state, "b") },
+		})
+}
+
+func TestApprovalStep(t *testing.T) {
+	approvals := make(chan Approval, 1)
+
+	approvals <- Approval{Approved: true, Approver: "alice"}
+	plan := testApprovalPlan(t, &ApprovalStep{Message: "deploy?", Approver: ChanApprover(approvals)})
+	require.NoError(t, plan.Execute(context.Background()))
+	require.Equal(t, "ab~a", plan.State().Get("test"))
+	require.Equal(t, Approval{Approved: true, Approver: "alice"}, plan.State().Get(DefaultApprovalStateKey))
+
+	approvals <- Approval{Approved: false, Approver: "bob", Comment: "not now"}
+	plan = testApprovalPlan(t, &ApprovalStep{Message: "deploy?", Approver: ChanApprover(approvals), StateKey: "deploy"})
+	err := plan.Execute(context.Background())
+	require.True(t, errors.Is(err, ErrApprovalDenied))
+	require.EqualError(t, err, "approval denied by bob: not now")
+	require.Equal(t, "a~a", plan.State().Get("test"))
+	require.Equal(t, Approval{Approved: false, Approver: "bob", Comment: "not now"}, plan.State().Get("deploy"))
+
+	plan = testApprovalPlan(t, &ApprovalStep{Approver: ChanApprover(approvals), Timeout: 10 * time.Millisecond})
+	require.Equal(t, ErrApprovalTimeout, plan.Execute(context.Background()))
+	require.Equal(t, "a~a", plan.State().Get("test"))
+}
+
+func TestPromptApprover(t *testing.T) {
+	var out bytes.Buffer
+
+	approver := NewPromptApprover(strings.NewReader("yes\nno\n"), &out, "alice")
+	req := ApprovalRequest{Step: "approval", Message: "deploy?"}
+
+	approval, err := approver.RequestApproval(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, Approval{Approved: true, Approver: "alice"}, approval)
+	require.Equal(t, "approval: deploy? [y/N] ", out.String())
+
+	approval, err = approver.RequestApproval(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, Approval{Approved: false, Approver: "alice"}, approval)
+
+	_, err = approver.RequestApproval(context.Background(), req)
+	require.Error(t, err)
+}
+
+func TestHTTPApprover(t *testing.T) {
+	tokens := map[string]string{"Bearer t0k3n": "alice"}
+
+	approver := NewHTTPApprover(func(r *http.Request) (string, error) {
+		if user, ok := tokens[r.Header.Get("Authorization")]; ok {
+			return user, nil
+		}
+		return "", errors.New("invalid token")
+	})
+	notified := make(chan ApprovalRequest, 1)
+	approver.Notify = func(_ context.Context, req ApprovalRequest) error {
+		notified <- req
+		return nil
+	}
+
+	server := httptest.NewServer(approver)
+	defer server.Close()
+
+	send := func(method, token, body string) int {
+		req, err := http.NewRequest(method, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		return res.StatusCode
+	}
+
+	require.Equal(t, http.StatusConflict, send(http.MethodPost, "t0k3n", `{"approved":true}`))
+
+	plan := testApprovalPlan(t, &ApprovalStep{Name: "sign-off", Message: "deploy?", Approver: approver})
+	run := plan.Start(context.Background())
+
+	require.Equal(t, ApprovalRequest{Step: "sign-off", Message: "deploy?"}, <-notified)
+
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "", ""))
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "t0k3n", ""))
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "", `{"approved":true,"approver":"mallory"}`))
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "nope", `{"approved":true,"approver":"mallory"}`))
+	require.Equal(t, http.StatusBadRequest, send(http.MethodPost, "t0k3n", `{"approved":`))
+
+	// The approver identity comes from the authentication, not from the decision.
+	require.Equal(t, http.StatusNoContent, send(http.MethodPost, "t0k3n", `{"approved":true,"approver":"mallory"}`))
+
+	require.NoError(t, run.Wait())
+	require.Equal(t, Approval{Approved: true, Approver: "alice"}, plan.State().Get(DefaultApprovalStateKey))
+
+	// Without authentication function, all requests are rejected.
+	server = httptest.NewServer(NewHTTPApprover(nil))
+	defer server.Close()
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "t0k3n", ""))
+}
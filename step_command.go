@@ -0,0 +1,283 @@
+package gsd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CommandError represents an error reported by a CommandStep if the command
+// exited with an unexpected exit code.
+type CommandError struct {
+	// Command is the command executed, including its arguments.
+	Command []string
+
+	// ExitCode is the exit code of the command.
+	ExitCode int
+
+	// Stderr is the standard error output of the command.
+	Stderr string
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("command %q exited with code %d", strings.Join(e.Command, " "), e.ExitCode)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+
+	return msg
+}
+
+// CommandStep is a Step implementation executing a command. If Templated is
+// set, the command, its arguments, environment and working directory are
+// templates rendered with the plan state values right before the execution
+// (see RenderTemplate). When the step's context is done (e.g. if the plan
+// execution is cancelled), the process group of the command is killed on Unix
+// systems (only the command process on other systems).
+type CommandStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Command is the command to execute, looked up in the PATH if it
+	// doesn't contain a path separator.
+	Command string `yaml:"command"`
+
+	// Args are the arguments of the command.
+	Args []string `yaml:"args"`
+
+	// Env are additional environment variables ("KEY=value") of the command,
+	// which inherits the environment of the current process.
+	Env []string `yaml:"env"`
+
+	// Dir is the working directory of the command. If empty, the command
+	// runs in the current directory.
+	Dir string `yaml:"dir"`
+
+	// StdinKey is an optional State key whose value (a string, a []byte or
+	// an io.Reader) is passed as the standard input of the command.
+	StdinKey string `yaml:"stdin_key"`
+
+	// StdoutKey is an optional State key under which the standard output of
+	// the command is stored as a string.
+	StdoutKey string `yaml:"stdout_key"`
+
+	// StderrKey is an optional State key under which the standard error
+	// output of the command is stored as a string.
+	StderrKey string `yaml:"stderr_key"`
+
+	// ExitCodeKey is an optional State key under which the exit code of the
+	// command is stored as an int.
+	ExitCodeKey string `yaml:"exit_code_key"`
+
+	// ExitCodes are the exit codes of the command considered successful. If
+	// empty, only 0 is considered successful. Other exit codes make the
+	// step fail with a *CommandError error.
+	ExitCodes []int `yaml:"exit_codes"`
+
+	// CleanupCommand is an optional command (with its arguments) executed
+	// during the step's cleanup phase, with the same environment and working
+	// directory as the command. Its failures are ignored.
+	CleanupCommand []string `yaml:"cleanup_command"`
+
+	// Templated indicates whether the command, its arguments, environment
+	// and working directory are templates.
+	Templated bool `yaml:"templated"`
+
+	retries int
+}
+
+func (s *CommandStep) PreExec(_ context.Context, _ *State) error {
+	if s.Command == "" {
+		return errors.New("command step has no command")
+	}
+
+	return nil
+}
+
+func (s *CommandStep) Exec(ctx context.Context, state *State) error {
+	var stdin io.Reader
+
+	if s.StdinKey != "" {
+		switch v := state.Get(s.StdinKey).(type) {
+		case string:
+			stdin = strings.NewReader(v)
+		case []byte:
+			stdin = bytes.NewReader(v)
+		case io.Reader:
+			stdin = v
+		case nil:
+			return fmt.Errorf("%w: %q", ErrKeyNotFound, s.StdinKey)
+		default:
+			return fmt.Errorf("unsupported standard input value of type %T", v)
+		}
+	}
+
+	cmd, err := s.command(state, append([]string{s.Command}, s.Args...))
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, &stdout, &stderr
+
+	exitCode, err := runCommand(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if s.StdoutKey != "" {
+		state.Store(s.StdoutKey, stdout.String())
+	}
+	if s.StderrKey != "" {
+		state.Store(s.StderrKey, stderr.String())
+	}
+	if s.ExitCodeKey != "" {
+		state.Store(s.ExitCodeKey, exitCode)
+	}
+
+	if !s.successful(exitCode) {
+		return &CommandError{
+			Command:  cmd.Args,
+			ExitCode: exitCode,
+			Stderr:   stderr.String(),
+		}
+	}
+
+	return nil
+}
+
+func (s *CommandStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *CommandStep) Cleanup(ctx context.Context, state *State) {
+	if len(s.CleanupCommand) == 0 {
+		return
+	}
+
+	cmd, err := s.command(state, s.CleanupCommand)
+	if err != nil {
+		return
+	}
+
+	_, _ = runCommand(ctx, cmd)
+}
+
+func (s *CommandStep) DryRun(_ context.Context, _ *State) (string, error) {
+	desc := "run command " + quoteCommand(s.Command, s.Args)
+	if s.Dir != "" {
+		desc += " in " + s.Dir
+	}
+
+	return desc, nil
+}
+
+func (s *CommandStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "run " + s.Command
+}
+
+func (s *CommandStep) Retries() int {
+	return s.retries
+}
+
+func (s *CommandStep) WithRetries(n int) Step {
+	s.retries = n
+	return s
+}
+
+// command returns the command argv (name and arguments) to execute with the
+// step's environment and working directory, rendered as templates with the
+// state values if the step is templated (see RenderTemplate).
+func (s *CommandStep) command(state *State, argv []string) (*exec.Cmd, error) {
+	var (
+		r    = newTemplateRenderer(state, s.Templated)
+		args = r.renderAll("args", argv)
+		env  = r.renderAll("env", s.Env)
+		dir  = r.render("dir", s.Dir)
+	)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...) // nolint:gosec
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	return cmd, nil
+}
+
+// runCommand runs the command cmd, and returns its exit code. A non-nil
+// error is returned if the command could not be executed or has been killed.
+func runCommand(ctx context.Context, cmd *exec.Cmd) (int, error) {
+	name := cmd.Args[0]
+
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return -1, fmt.Errorf("unable to start command %q: %w", name, err)
+	}
+
+	waitCh := make(chan error, 1)
+	go func() { waitCh <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-waitCh:
+
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-waitCh
+		return -1, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("command %q failed: %w", name, err)
+	}
+
+	return 0, nil
+}
+
+func (s *CommandStep) successful(exitCode int) bool {
+	if len(s.ExitCodes) == 0 {
+		return exitCode == 0
+	}
+
+	for _, c := range s.ExitCodes {
+		if c == exitCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quoteCommand returns the command name with its arguments args, quoted if
+// they contain whitespaces or quotes.
+func quoteCommand(name string, args []string) string {
+	quoted := make([]string, 0, len(args)+1)
+
+	for _, a := range append([]string{name}, args...) {
+		if a == "" || strings.ContainsAny(a, " \t\n\"'") {
+			a = strconv.Quote(a)
+		}
+		quoted = append(quoted, a)
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,14 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+
+package gsd
+
+import "os/exec"
+
+// setProcessGroup is a no-op on systems not supporting process groups.
+func setProcessGroup(_ *exec.Cmd) {}
+
+// killProcessGroup kills the process of the started command cmd: its child
+// processes are not killed on systems not supporting process groups.
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,160 @@
+//go:build linux
+
+package gsd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandStep(t *testing.T) {
+	dir := t.TempDir()
+
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	plan.State().Store("input", "hello")
+	plan.State().Store("who", "world")
+	plan.State().Store("exit", 3)
+	plan.AddStep(&CommandStep{
+		Command:        "sh",
+		Args:           []string{"-c", `cat; echo " $WHO"; pwd >&2; exit {{ .State.exit }}`},
+		Env:            []string{"WHO={{ .State.who }}"},
+		Dir:            dir,
+		StdinKey:       "input",
+		StdoutKey:      "stdout",
+		StderrKey:      "stderr",
+		ExitCodeKey:    "code",
+		ExitCodes:      []int{0, 3},
+		CleanupCommand: []string{"touch", "cleaned"},
+		Templated:      true,
+	})
+
+	require.NoError(t, plan.Execute(context.Background()))
+	require.Equal(t, "hello world\n", plan.State().Get("stdout"))
+	require.Contains(t, plan.State().Get("stderr"), filepath.Base(dir))
+	require.Equal(t, 3, plan.State().Get("code"))
+	require.FileExists(t, filepath.Join(dir, "cleaned"))
+}
+
+func TestCommandStep_Retries(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	plan, err := NewPlan(PlanOptContinueOnError())
+	require.NoError(t, err)
+
+	// The command fails on its first attempt only, and must not be executed
+	// again once successful.
+	plan.AddStep((&CommandStep{
+		Command: "sh",
+		Args:    []string{"-c", `echo x >> out; test $(wc -l < out) -gt 1`},
+		Dir:     dir,
+	}).WithRetries(3))
+
+	require.NoError(t, plan.Execute(context.Background()))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "x\nx\n", string(data))
+
+	// A command succeeding straight away is executed once.
+	require.NoError(t, os.Remove(out))
+
+	plan, err = NewPlan(PlanOptContinueOnError())
+	require.NoError(t, err)
+
+	plan.AddStep((&CommandStep{Command: "sh", Args: []string{"-c", "echo x >> out"}, Dir: dir}).WithRetries(2))
+
+	require.NoError(t, plan.Execute(context.Background()))
+	data, err = os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "x\n", string(data))
+}
+
+func TestCommandStep_Reused(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	step := &CommandStep{Command: "sh", Args: []string{"-c", "echo x >> out"}, Dir: dir}
+
+	// The plan times out before its cleanup phase, the step must still be
+	// executed by a subsequent plan.
+	plan, err := NewPlan(PlanOptLimitDuration(100 * time.Millisecond))
+	require.NoError(t, err)
+
+	plan.
+		AddStep(step).
+		AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+	require.EqualError(t, plan.Execute(context.Background()), ErrTimeout.Error())
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	require.NoError(t, plan.AddStep(step).Execute(context.Background()))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "x\nx\n", string(data))
+}
+
+func TestCommandStep_NotTemplated(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	// The parameters of a step not templated are passed as is.
+	plan.AddStep(&CommandStep{Command: "echo", Args: []string{"--format", "{{.ID}}"}, StdoutKey: "stdout"})
+
+	require.NoError(t, plan.Execute(context.Background()))
+	require.Equal(t, "--format {{.ID}}\n", plan.State().Get("stdout"))
+}
+
+func TestCommandStep_ExitCode(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	plan.AddStep(&CommandStep{Command: "sh", Args: []string{"-c", "echo oops >&2; exit 1"}})
+
+	err = plan.Execute(context.Background())
+	var cmdErr *CommandError
+	require.True(t, errors.As(err, &cmdErr))
+	require.Equal(t, 1, cmdErr.ExitCode)
+	require.EqualError(t, err, `command "sh -c echo oops >&2; exit 1" exited with code 1: oops`)
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	plan.AddStep(&CommandStep{Command: filepath.Join(t.TempDir(), "nonexistent")})
+	require.Error(t, plan.Execute(context.Background()))
+}
+
+func TestCommandStep_Cancel(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	plan, err := NewPlan(PlanOptLimitDuration(500 * time.Millisecond))
+	require.NoError(t, err)
+
+	// The child process must be killed along with the shell.
+	plan.AddStep(&CommandStep{Command: "sh", Args: []string{"-c", "sleep 10 & echo $! > " + pidFile + "; wait"}})
+
+	start := time.Now()
+	require.Equal(t, ErrTimeout, plan.Execute(context.Background()))
+	require.True(t, time.Since(start) < 5*time.Second)
+
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		// The process is gone, or a zombie waiting to be reaped.
+		stat, err := os.ReadFile(filepath.Join("/proc", strings.TrimSpace(string(data)), "stat"))
+		return err != nil || strings.Contains(string(stat), ") Z ")
+	}, 2*time.Second, 10*time.Millisecond)
+}
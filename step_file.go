@@ -0,0 +1,693 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The file steps (WriteFileStep, CopyFileStep, MoveFileStep,
+// TemplateFileStep, MkdirStep, SymlinkStep and ChmodStep) paths are templates
+// rendered with the plan state values right before the step execution if
+// their Templated field is set (see RenderTemplate). The file steps roll back
+// their changes during the plan cleanup phase if the plan execution failed
+// (see PlanError), restoring the files they have overwritten from backups. If
+// the plan execution succeeded, the backups are deleted and the changes are
+// kept. A file step failing rolls back its own changes right away.
+//
+// Note: the rollback data is only kept in memory, the file steps completed
+// before the checkpoint a plan execution is resumed from (see Plan.Resume)
+// cannot roll back their changes, and their backups are left in place.
+
+// fileRollback records the changes made by a file step, allowing to roll
+// them back.
+type fileRollback struct {
+	saved   map[string]bool
+	undo    []func() error
+	discard []func()
+}
+
+// backup records the current state of the file at path (if any) before its
+// modification, so that it can be restored. A path is only backed up once,
+// so that the changes of the step's retries are rolled back as a whole.
+func (r *fileRollback) backup(path string) error {
+	if r.saved[path] {
+		return nil
+	}
+
+	fi, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		r.onUndo(func() error { return removeIfExists(path) })
+
+	case err != nil:
+		return err
+
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		r.onUndo(func() error {
+			if err := removeIfExists(path); err != nil {
+				return err
+			}
+			return os.Symlink(target, path)
+		})
+
+	case fi.Mode().IsRegular():
+		f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".gsd-backup-*")
+		if err != nil {
+			return fmt.Errorf("unable to back up %s: %w", path, err)
+		}
+		backup := f.Name()
+		_ = f.Close()
+
+		if err := copyFile(path, backup, fi.Mode().Perm()); err != nil {
+			_ = os.Remove(backup)
+			return fmt.Errorf("unable to back up %s: %w", path, err)
+		}
+		r.onUndo(func() error { return os.Rename(backup, path) })
+		r.discard = append(r.discard, func() { _ = os.Remove(backup) })
+
+	default:
+		return fmt.Errorf("unable to back up %s: not a regular file nor a symbolic link", path)
+	}
+
+	if r.saved == nil {
+		r.saved = make(map[string]bool)
+	}
+	r.saved[path] = true
+
+	return nil
+}
+
+// onUndo records the function f undoing a change.
+func (r *fileRollback) onUndo(f func() error) {
+	r.undo = append(r.undo, f)
+}
+
+// exec executes the function f changing files, rolling back the changes if
+// it fails.
+func (r *fileRollback) exec(f func() error) error {
+	if err := f(); err != nil {
+		r.rollback()
+		return err
+	}
+
+	return nil
+}
+
+// cleanup rolls back the recorded changes if the plan execution failed, or
+// deletes the backups otherwise.
+func (r *fileRollback) cleanup(ctx context.Context) {
+	if PlanError(ctx) != nil {
+		r.rollback()
+		return
+	}
+
+	for _, f := range r.discard {
+		f()
+	}
+
+	*r = fileRollback{}
+}
+
+// rollback rolls back the recorded changes in reverse order, and deletes the
+// remaining backups.
+func (r *fileRollback) rollback() {
+	for i := len(r.undo) - 1; i >= 0; i-- {
+		_ = r.undo[i]()
+	}
+
+	for _, f := range r.discard {
+		f()
+	}
+
+	*r = fileRollback{}
+}
+
+// removeIfExists removes the file at path, if it exists.
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// copyFile copies the content of the file src to the file dst, created or
+// truncated with the permissions perm.
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return writeFile(dst, perm, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
+
+// writeFile creates or truncates the file at path with the permissions perm,
+// and writes its content using the function write.
+func writeFile(path string, perm os.FileMode, write func(io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Existing files keep their permissions when truncated.
+	return os.Chmod(path, perm)
+}
+
+// fileMode returns the mode m, or def if m is zero.
+func fileMode(m, def os.FileMode) os.FileMode {
+	if m == 0 {
+		return def
+	}
+
+	return m
+}
+
+// WriteFileStep is a Step implementation writing a file.
+type WriteFileStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Path is the path of the file.
+	Path string `yaml:"path"`
+
+	// Content is the content of the file.
+	Content []byte `yaml:"-"`
+
+	// Mode is the permissions of the file. If zero, 0644 is used.
+	Mode os.FileMode `yaml:"mode"`
+
+	// Templated indicates whether the paths are templates.
+	Templated bool `yaml:"templated"`
+
+	rollback fileRollback
+}
+
+func (s *WriteFileStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *WriteFileStep) Exec(_ context.Context, state *State) error {
+	return s.rollback.exec(func() error {
+		var (
+			r    = newTemplateRenderer(state, s.Templated)
+			path = r.render("path", s.Path)
+		)
+		if r.err != nil {
+			return r.err
+		}
+
+		if err := s.rollback.backup(path); err != nil {
+			return err
+		}
+
+		return writeFile(path, fileMode(s.Mode, 0o644), func(w io.Writer) error {
+			_, err := w.Write(s.Content)
+			return err
+		})
+	})
+}
+
+func (s *WriteFileStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *WriteFileStep) Cleanup(ctx context.Context, _ *State) {
+	s.rollback.cleanup(ctx)
+}
+
+func (s *WriteFileStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return fmt.Sprintf("write %d bytes to file %s", len(s.Content), s.Path), nil
+}
+
+func (s *WriteFileStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "write " + s.Path
+}
+
+func (s *WriteFileStep) Retries() int {
+	return 0
+}
+
+// TemplateFileStep is a Step implementation writing a file rendered from a
+// template with the plan state values (see RenderTemplate).
+type TemplateFileStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Path is the path of the file.
+	Path string `yaml:"path"`
+
+	// Template is the template of the file content.
+	Template string `yaml:"template"`
+
+	// Mode is the permissions of the file. If zero, 0644 is used.
+	Mode os.FileMode `yaml:"mode"`
+
+	// Templated indicates whether the paths are templates.
+	Templated bool `yaml:"templated"`
+
+	rollback fileRollback
+}
+
+func (s *TemplateFileStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *TemplateFileStep) Exec(_ context.Context, state *State) error {
+	return s.rollback.exec(func() error {
+		var (
+			r    = newTemplateRenderer(state, s.Templated)
+			path = r.render("path", s.Path)
+		)
+		if r.err != nil {
+			return r.err
+		}
+
+		content, err := renderTemplate("file", s.Template, newTemplateData(state))
+		if err != nil {
+			return err
+		}
+
+		if err := s.rollback.backup(path); err != nil {
+			return err
+		}
+
+		return writeFile(path, fileMode(s.Mode, 0o644), func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+	})
+}
+
+func (s *TemplateFileStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *TemplateFileStep) Cleanup(ctx context.Context, _ *State) {
+	s.rollback.cleanup(ctx)
+}
+
+func (s *TemplateFileStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return "render template to file " + s.Path, nil
+}
+
+func (s *TemplateFileStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "render " + s.Path
+}
+
+func (s *TemplateFileStep) Retries() int {
+	return 0
+}
+
+// CopyFileStep is a Step implementation copying a file.
+type CopyFileStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Src is the path of the file to copy.
+	Src string `yaml:"src"`
+
+	// Dst is the path of the copy.
+	Dst string `yaml:"dst"`
+
+	// Mode is the permissions of the copy. If zero, the permissions of the
+	// source file are used.
+	Mode os.FileMode `yaml:"mode"`
+
+	// Templated indicates whether the paths are templates.
+	Templated bool `yaml:"templated"`
+
+	rollback fileRollback
+}
+
+func (s *CopyFileStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *CopyFileStep) Exec(_ context.Context, state *State) error {
+	return s.rollback.exec(func() error {
+		var (
+			r   = newTemplateRenderer(state, s.Templated)
+			src = r.render("src", s.Src)
+			dst = r.render("dst", s.Dst)
+		)
+		if r.err != nil {
+			return r.err
+		}
+
+		fi, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+
+		if err := s.rollback.backup(dst); err != nil {
+			return err
+		}
+
+		return copyFile(src, dst, fileMode(s.Mode, fi.Mode().Perm()))
+	})
+}
+
+func (s *CopyFileStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *CopyFileStep) Cleanup(ctx context.Context, _ *State) {
+	s.rollback.cleanup(ctx)
+}
+
+func (s *CopyFileStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return fmt.Sprintf("copy file %s to %s", s.Src, s.Dst), nil
+}
+
+func (s *CopyFileStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "copy " + s.Src
+}
+
+func (s *CopyFileStep) Retries() int {
+	return 0
+}
+
+// MoveFileStep is a Step implementation moving (renaming) a file. Note: the
+// source and destination must be on the same file system.
+type MoveFileStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Src is the path of the file to move.
+	Src string `yaml:"src"`
+
+	// Dst is the destination path of the file.
+	Dst string `yaml:"dst"`
+
+	// Templated indicates whether the paths are templates.
+	Templated bool `yaml:"templated"`
+
+	rollback fileRollback
+}
+
+func (s *MoveFileStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *MoveFileStep) Exec(_ context.Context, state *State) error {
+	return s.rollback.exec(func() error {
+		var (
+			r   = newTemplateRenderer(state, s.Templated)
+			src = r.render("src", s.Src)
+			dst = r.render("dst", s.Dst)
+		)
+		if r.err != nil {
+			return r.err
+		}
+
+		if err := s.rollback.backup(dst); err != nil {
+			return err
+		}
+
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+		s.rollback.onUndo(func() error { return os.Rename(dst, src) })
+
+		return nil
+	})
+}
+
+func (s *MoveFileStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *MoveFileStep) Cleanup(ctx context.Context, _ *State) {
+	s.rollback.cleanup(ctx)
+}
+
+func (s *MoveFileStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return fmt.Sprintf("move file %s to %s", s.Src, s.Dst), nil
+}
+
+func (s *MoveFileStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "move " + s.Src
+}
+
+func (s *MoveFileStep) Retries() int {
+	return 0
+}
+
+// MkdirStep is a Step implementation creating a directory, along with any
+// missing parent directories. Only the directories created by the step are
+// removed when rolled back, provided they are empty.
+type MkdirStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Path is the path of the directory.
+	Path string `yaml:"path"`
+
+	// Mode is the permissions of the directories created. If zero, 0755 is
+	// used.
+	Mode os.FileMode `yaml:"mode"`
+
+	// Templated indicates whether the paths are templates.
+	Templated bool `yaml:"templated"`
+
+	rollback fileRollback
+}
+
+func (s *MkdirStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *MkdirStep) Exec(_ context.Context, state *State) error {
+	return s.rollback.exec(func() error {
+		var (
+			r    = newTemplateRenderer(state, s.Templated)
+			path = r.render("path", s.Path)
+		)
+		if r.err != nil {
+			return r.err
+		}
+
+		// Record the missing directories, from the deepest one.
+		var missing []string
+		for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+			if _, err := os.Lstat(dir); err == nil || !errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			missing = append(missing, dir)
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+
+		for i := len(missing) - 1; i >= 0; i-- {
+			dir := missing[i]
+			if err := os.Mkdir(dir, fileMode(s.Mode, 0o755)); err != nil {
+				return err
+			}
+			s.rollback.onUndo(func() error { return os.Remove(dir) })
+		}
+
+		return nil
+	})
+}
+
+func (s *MkdirStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *MkdirStep) Cleanup(ctx context.Context, _ *State) {
+	s.rollback.cleanup(ctx)
+}
+
+func (s *MkdirStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return "create directory " + s.Path, nil
+}
+
+func (s *MkdirStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "mkdir " + s.Path
+}
+
+func (s *MkdirStep) Retries() int {
+	return 0
+}
+
+// SymlinkStep is a Step implementation creating a symbolic link, replacing
+// the file at its path (if any).
+type SymlinkStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Target is the target of the link.
+	Target string `yaml:"target"`
+
+	// Path is the path of the link.
+	Path string `yaml:"path"`
+
+	// Templated indicates whether the paths are templates.
+	Templated bool `yaml:"templated"`
+
+	rollback fileRollback
+}
+
+func (s *SymlinkStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *SymlinkStep) Exec(_ context.Context, state *State) error {
+	return s.rollback.exec(func() error {
+		var (
+			r      = newTemplateRenderer(state, s.Templated)
+			target = r.render("target", s.Target)
+			path   = r.render("path", s.Path)
+		)
+		if r.err != nil {
+			return r.err
+		}
+
+		if err := s.rollback.backup(path); err != nil {
+			return err
+		}
+
+		if err := removeIfExists(path); err != nil {
+			return err
+		}
+
+		return os.Symlink(target, path)
+	})
+}
+
+func (s *SymlinkStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *SymlinkStep) Cleanup(ctx context.Context, _ *State) {
+	s.rollback.cleanup(ctx)
+}
+
+func (s *SymlinkStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return fmt.Sprintf("create symbolic link %s to %s", s.Path, s.Target), nil
+}
+
+func (s *SymlinkStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "symlink " + s.Path
+}
+
+func (s *SymlinkStep) Retries() int {
+	return 0
+}
+
+// ChmodStep is a Step implementation changing the permissions of a file.
+type ChmodStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Path is the path of the file.
+	Path string `yaml:"path"`
+
+	// Mode is the new permissions of the file.
+	Mode os.FileMode `yaml:"mode"`
+
+	// Templated indicates whether the paths are templates.
+	Templated bool `yaml:"templated"`
+
+	rollback fileRollback
+}
+
+func (s *ChmodStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *ChmodStep) Exec(_ context.Context, state *State) error {
+	return s.rollback.exec(func() error {
+		var (
+			r    = newTemplateRenderer(state, s.Templated)
+			path = r.render("path", s.Path)
+		)
+		if r.err != nil {
+			return r.err
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		if !s.rollback.saved[path] {
+			prev := fi.Mode().Perm()
+			s.rollback.onUndo(func() error { return os.Chmod(path, prev) })
+			s.rollback.saved = map[string]bool{path: true}
+		}
+
+		return os.Chmod(path, s.Mode)
+	})
+}
+
+func (s *ChmodStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *ChmodStep) Cleanup(ctx context.Context, _ *State) {
+	s.rollback.cleanup(ctx)
+}
+
+func (s *ChmodStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return fmt.Sprintf("change permissions of file %s to %s", s.Path, s.Mode), nil
+}
+
+func (s *ChmodStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return "chmod " + s.Path
+}
+
+func (s *ChmodStep) Retries() int {
+	return 0
+}
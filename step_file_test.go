@@ -0,0 +1,136 @@
+//go:build linux
+
+package gsd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testFileTree returns the files of the directory dir, as a map of relative
+// paths to content (files), link target (symbolic links) or "<dir>".
+func testFileTree(t *testing.T, dir string) map[string]string {
+	tree := make(map[string]string)
+
+	require.NoError(t, filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		require.NoError(t, err)
+		rel, _ := filepath.Rel(dir, path)
+
+		switch {
+		case path == dir:
+		case fi.IsDir():
+			tree[rel] = "<dir>"
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			require.NoError(t, err)
+			tree[rel] = "-> " + target
+		default:
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			tree[rel] = fi.Mode().Perm().String() + " " + string(data)
+		}
+		return nil
+	}))
+
+	return tree
+}
+
+func testFilePlan(t *testing.T, dir string, fail bool) *Plan {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	plan.State().Store("version", "2")
+	plan.State().Store("dir", "d")
+
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	plan.
+		AddStep(&MkdirStep{Path: path("conf/{{ .State.dir }}"), Templated: true}).
+		AddStep(&WriteFileStep{Path: path("app.conf"), Content: []byte("v2"), Mode: 0o600}).
+		AddStep(&TemplateFileStep{Path: path("conf/d/version"), Template: "version={{ .State.version }}"}).
+		AddStep(&CopyFileStep{Src: path("app.conf"), Dst: path("app.conf.copy")}).
+		AddStep(&MoveFileStep{Src: path("old.conf"), Dst: path("new.conf")}).
+		AddStep(&SymlinkStep{Target: "app.conf", Path: path("current")}).
+		AddStep(&ChmodStep{Path: path("script.sh"), Mode: 0o755})
+
+	if fail {
+		plan.AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+		})
+	}
+
+	return plan
+}
+
+func testFileSetup(t *testing.T) string {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.conf"), []byte("v1"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.conf.copy"), []byte("old copy"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.conf"), []byte("old"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "script.sh"), []byte("#!/bin/sh"), 0o644))
+	require.NoError(t, os.Symlink("old.conf", filepath.Join(dir, "current")))
+
+	return dir
+}
+
+func TestFileSteps(t *testing.T) {
+	dir := testFileSetup(t)
+
+	require.NoError(t, testFilePlan(t, dir, false).Execute(context.Background()))
+	require.Equal(t, map[string]string{
+		"app.conf":       "-rw------- v2",
+		"app.conf.copy":  "-rw------- v2",
+		"conf":           "<dir>",
+		"conf/d":         "<dir>",
+		"conf/d/version": "-rw-r--r-- version=2",
+		"current":        "-> app.conf",
+		"new.conf":       "-rw-r--r-- old",
+		"script.sh":      "-rwxr-xr-x #!/bin/sh",
+	}, testFileTree(t, dir))
+}
+
+func TestFileSteps_Rollback(t *testing.T) {
+	dir := testFileSetup(t)
+	before := testFileTree(t, dir)
+
+	require.Error(t, testFilePlan(t, dir, true).Execute(context.Background()))
+	require.Equal(t, before, testFileTree(t, dir))
+}
+
+func TestFileSteps_RollbackFailedStep(t *testing.T) {
+	dir := testFileSetup(t)
+	before := testFileTree(t, dir)
+
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	// Copying a directory fails after the destination file has been
+	// truncated, the failed step must restore it.
+	plan.AddStep(&CopyFileStep{Src: dir, Dst: filepath.Join(dir, "app.conf")})
+
+	require.Error(t, plan.Execute(context.Background()))
+	require.Equal(t, before, testFileTree(t, dir))
+}
+
+func TestFileSteps_Retries(t *testing.T) {
+	dir := testFileSetup(t)
+
+	plan, err := NewPlan(PlanOptContinueOnError())
+	require.NoError(t, err)
+
+	// The file must not be moved again (i.e. fail) once moved successfully.
+	plan.AddStep(&loadedStep{
+		WrappedStep: WrappedStep{Step: &MoveFileStep{Src: filepath.Join(dir, "old.conf"), Dst: filepath.Join(dir, "new.conf")}},
+		retries:     func(n int) *int { return &n }(2),
+	})
+
+	require.NoError(t, plan.Execute(context.Background()))
+	require.FileExists(t, filepath.Join(dir, "new.conf"))
+	require.NoFileExists(t, filepath.Join(dir, "old.conf"))
+}
@@ -4,14 +4,23 @@ import "context"
 
 // GenericStep is a generic Step implementation allowing users to provide
 // arbitrary pre-exec/exec/post-exec/cleanup functions to be executed during
-// the step's evaluation.
+// the step's evaluation, and an optional dry-run function describing them.
 type GenericStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string
+
+	// Tags are optional tags allowing to select the step for execution, see
+	// PlanOptIncludeTags and PlanOptExcludeTags.
+	Tags []string
+
 	PreExecFunc  func(context.Context, *State) error
 	ExecFunc     func(context.Context, *State) error
 	PostExecFunc func(context.Context, *State) error
 	CleanupFunc  func(context.Context, *State)
+	DryRunFunc   func(context.Context, *State) (string, error)
 
 	retries    int
+	weight     float64
 	preExecOK  bool
 	execOK     bool
 	postExecOK bool
@@ -53,6 +62,22 @@ func (s *GenericStep) Cleanup(ctx context.Context, state *State) {
 	}
 }
 
+func (s *GenericStep) DryRun(ctx context.Context, state *State) (string, error) {
+	if s.DryRunFunc != nil {
+		return s.DryRunFunc(ctx, state)
+	}
+
+	return "", nil
+}
+
+func (s *GenericStep) StepName() string {
+	return s.Name
+}
+
+func (s *GenericStep) StepTags() []string {
+	return s.Tags
+}
+
 func (s *GenericStep) Retries() int {
 	return s.retries
 }
@@ -61,3 +86,16 @@ func (s *GenericStep) WithRetries(n int) Step {
 	s.retries = n
 	return s
 }
+
+func (s *GenericStep) Weight() float64 {
+	return s.weight
+}
+
+func (s *GenericStep) WithWeight(w float64) Step {
+	s.weight = w
+	return s
+}
+
+func (s *GenericStep) With(mw ...Middleware) Step {
+	return Wrap(s, mw...)
+}
@@ -0,0 +1,251 @@
+package gsd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ErrHTTPAssertion represents an error reported by an HTTPStep if the
+// response doesn't match the step's expectations.
+var ErrHTTPAssertion = errors.New("unexpected HTTP response")
+
+// HTTPResponse represents an HTTP response received by an HTTPStep, as
+// stored in the plan state.
+type HTTPResponse struct {
+	// StatusCode is the status code of the response.
+	StatusCode int `json:"status_code" yaml:"status_code"`
+
+	// Header is the header of the response.
+	Header http.Header `json:"header" yaml:"header"`
+
+	// Body is the body of the response.
+	Body string `json:"body" yaml:"body"`
+
+	// JSON is the body of the response decoded as JSON, or nil if the body
+	// is not valid JSON.
+	JSON interface{} `json:"json,omitempty" yaml:"json,omitempty"`
+}
+
+// HTTPStep is a Step implementation performing an HTTP request and checking
+// the response, e.g. to check the health of a service or call an API. If
+// Templated is set, the URL, the header values and the body of the request
+// are templates rendered with the plan state values right before the request
+// (see RenderTemplate).
+type HTTPStep struct {
+	// Name is an optional human-readable name identifying the step.
+	Name string `yaml:"-"`
+
+	// Method is the request method. If empty, GET is used.
+	Method string `yaml:"method"`
+
+	// URL is the request URL.
+	URL string `yaml:"url"`
+
+	// Header are the request header fields.
+	Header map[string]string `yaml:"header"`
+
+	// Body is the request body.
+	Body string `yaml:"body"`
+
+	// Client is the HTTP client used to perform the request. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client `yaml:"-"`
+
+	// ExpectStatus are the expected response status codes. If empty, any
+	// 2xx status code is expected.
+	ExpectStatus []int `yaml:"expect_status"`
+
+	// ExpectJSON are the values expected in the response body decoded as
+	// JSON, keyed by JSONPath expression (e.g. "$.items[0].name"). Only
+	// child members and array indices are supported in the expressions.
+	ExpectJSON map[string]interface{} `yaml:"expect_json"`
+
+	// ExpectBody is an optional regular expression the response body must
+	// match.
+	ExpectBody string `yaml:"expect_body"`
+
+	// ResponseKey is an optional State key under which the response is
+	// stored as an HTTPResponse value.
+	ResponseKey string `yaml:"response_key"`
+
+	// Templated indicates whether the URL, the header values and the body
+	// of the request are templates.
+	Templated bool `yaml:"templated"`
+
+	retries int
+}
+
+func (s *HTTPStep) PreExec(_ context.Context, _ *State) error {
+	if s.URL == "" {
+		return errors.New("HTTP step has no URL")
+	}
+
+	if s.ExpectBody != "" {
+		if _, err := regexp.Compile(s.ExpectBody); err != nil {
+			return fmt.Errorf("invalid body regexp: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *HTTPStep) Exec(ctx context.Context, state *State) error {
+	req, err := s.request(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read HTTP response body: %w", err)
+	}
+
+	response := HTTPResponse{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       string(body),
+	}
+	if err := json.Unmarshal(body, &response.JSON); err != nil {
+		response.JSON = nil
+	}
+
+	if s.ResponseKey != "" {
+		state.Store(s.ResponseKey, response)
+	}
+
+	return s.check(&response)
+}
+
+func (s *HTTPStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *HTTPStep) Cleanup(_ context.Context, _ *State) {}
+
+func (s *HTTPStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return fmt.Sprintf("send HTTP request %s %s", s.method(), s.URL), nil
+}
+
+func (s *HTTPStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return s.method() + " " + s.URL
+}
+
+func (s *HTTPStep) Retries() int {
+	return s.retries
+}
+
+func (s *HTTPStep) WithRetries(n int) Step {
+	s.retries = n
+	return s
+}
+
+func (s *HTTPStep) method() string {
+	if s.Method != "" {
+		return s.Method
+	}
+
+	return http.MethodGet
+}
+
+// request returns the step's HTTP request, rendered with the state values if
+// the step is templated.
+func (s *HTTPStep) request(ctx context.Context, state *State) (*http.Request, error) {
+	var (
+		r   = newTemplateRenderer(state, s.Templated)
+		url = r.render("URL", s.URL)
+	)
+
+	var body io.Reader
+	if s.Body != "" {
+		body = strings.NewReader(r.render("body", s.Body))
+	}
+
+	header := make(http.Header)
+	for k, v := range s.Header {
+		header.Set(k, r.render("header "+k, v))
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, s.method(), url, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP request: %w", err)
+	}
+	req.Header = header
+
+	return req, nil
+}
+
+// check checks that the response res matches the step's expectations.
+func (s *HTTPStep) check(res *HTTPResponse) error {
+	if !s.expectedStatus(res.StatusCode) {
+		return fmt.Errorf("%w: status code %d", ErrHTTPAssertion, res.StatusCode)
+	}
+
+	for path, expected := range s.ExpectJSON {
+		if res.JSON == nil {
+			return fmt.Errorf("%w: body is not valid JSON", ErrHTTPAssertion)
+		}
+
+		actual, err := evalJSONPath(res.JSON, path)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrHTTPAssertion, err)
+		}
+
+		// Normalize the expected value as decoded JSON, e.g. int to float64.
+		var normalized interface{}
+		if data, err := json.Marshal(expected); err == nil {
+			_ = json.Unmarshal(data, &normalized)
+		}
+
+		if !reflect.DeepEqual(normalized, actual) {
+			return fmt.Errorf("%w: JSON path %q: expected %v, got %v", ErrHTTPAssertion, path, expected, actual)
+		}
+	}
+
+	if s.ExpectBody != "" {
+		if !regexp.MustCompile(s.ExpectBody).MatchString(res.Body) {
+			return fmt.Errorf("%w: body doesn't match regexp %q", ErrHTTPAssertion, s.ExpectBody)
+		}
+	}
+
+	return nil
+}
+
+func (s *HTTPStep) expectedStatus(code int) bool {
+	if len(s.ExpectStatus) == 0 {
+		return code >= 200 && code < 300
+	}
+
+	for _, c := range s.ExpectStatus {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
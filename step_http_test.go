@@ -0,0 +1,162 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPStep(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"method":%q,"path":%q,"token":%q,"body":%q,"items":[{"id":1},{"id":2}]}`,
+			r.Method, r.URL.Path, r.Header.Get("X-Token"), body)
+	}))
+	defer server.Close()
+
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	plan.State().Store("url", server.URL)
+	plan.State().Store("version", "1.2.3")
+	plan.State().Store("token", "s3cr3t")
+	plan.AddStep(&HTTPStep{
+		Method:    http.MethodPost,
+		URL:       "{{ .State.url }}/deploy",
+		Header:    map[string]string{"X-Token": "{{ .State.token }}"},
+		Body:      `{"version":"{{ .State.version }}"}`,
+		Templated: true,
+		ExpectJSON: map[string]interface{}{
+			"$.method":       "POST",
+			"$.path":         "/deploy",
+			"$['token']":     "s3cr3t",
+			"$.items[-1].id": 2,
+		},
+		ExpectBody:  `"body":".*1\.2\.3`,
+		ResponseKey: "response",
+	})
+
+	require.NoError(t, plan.Execute(context.Background()))
+
+	res, ok := plan.State().Get("response").(HTTPResponse)
+	require.True(t, ok)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	require.Equal(t, "POST", res.JSON.(map[string]interface{})["method"])
+}
+
+func TestHTTPStep_Assertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"pending"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		step HTTPStep
+		err  bool
+	}{
+		{name: "default status", step: HTTPStep{}},
+		{name: "unexpected status", step: HTTPStep{ExpectStatus: []int{http.StatusOK}}, err: true},
+		{name: "unexpected JSON value", step: HTTPStep{ExpectJSON: map[string]interface{}{"$.status": "done"}}, err: true},
+		{name: "missing JSON member", step: HTTPStep{ExpectJSON: map[string]interface{}{"$.id": 1}}, err: true},
+		{name: "unmatched body", step: HTTPStep{ExpectBody: "done"}, err: true},
+		{name: "missing template key", step: HTTPStep{Body: "{{ .State.missing }}", Templated: true}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := NewPlan()
+			require.NoError(t, err)
+
+			step := tt.step
+			step.URL = server.URL
+			plan.AddStep(&step)
+
+			err = plan.Execute(context.Background())
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestHTTPStep_Retries(t *testing.T) {
+	var (
+		calls    int32
+		failures int32
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		failures int32
+		calls    int32
+	}{
+		{name: "success", failures: 0, calls: 1},
+		{name: "one failure", failures: 1, calls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			atomic.StoreInt32(&failures, tt.failures)
+
+			plan, err := NewPlan(PlanOptContinueOnError())
+			require.NoError(t, err)
+
+			plan.AddStep((&HTTPStep{Method: http.MethodPost, URL: server.URL}).WithRetries(3))
+			require.NoError(t, plan.Execute(context.Background()))
+			require.Equal(t, tt.calls, atomic.LoadInt32(&calls))
+		})
+	}
+
+	// A step reused after a plan timed out before its cleanup phase must
+	// send its request again.
+	atomic.StoreInt32(&calls, 0)
+	step := &HTTPStep{URL: server.URL}
+
+	plan, err := NewPlan(PlanOptLimitDuration(100 * time.Millisecond))
+	require.NoError(t, err)
+
+	plan.
+		AddStep(step).
+		AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+	require.EqualError(t, plan.Execute(context.Background()), ErrTimeout.Error())
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	require.NoError(t, plan.AddStep(step).Execute(context.Background()))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	plan.AddStep(&HTTPStep{URL: server.URL, ExpectStatus: []int{http.StatusNotFound}})
+	require.True(t, errors.Is(plan.Execute(context.Background()), ErrHTTPAssertion))
+}
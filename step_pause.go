@@ -6,7 +6,8 @@ import (
 )
 
 // pauseStep is a "virtual" Step implementation used as a convenient
-// alternative to implementing a time.Sleep call in a GenericStep struct.
+// alternative to implementing a time.Sleep call in a GenericStep struct. The
+// pause is interrupted if the plan execution context is done.
 // This also offers the benefit of detecting when a step being executed is
 // a pause step, allowing to skip it during the cleanup phase.
 type pauseStep struct {
@@ -17,9 +18,17 @@ func (s *pauseStep) PreExec(_ context.Context, _ *State) error {
 	return nil
 }
 
-func (s *pauseStep) Exec(_ context.Context, _ *State) error {
-	time.Sleep(s.d)
-	return nil
+func (s *pauseStep) Exec(ctx context.Context, _ *State) error {
+	timer := ContextClock(ctx).NewTimer(s.d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (s *pauseStep) PostExec(_ context.Context, _ *State) error {
@@ -28,6 +37,23 @@ func (s *pauseStep) PostExec(_ context.Context, _ *State) error {
 
 func (s *pauseStep) Cleanup(_ context.Context, _ *State) {}
 
+func (s *pauseStep) DryRun(_ context.Context, _ *State) (string, error) {
+	return "pause for " + s.d.String(), nil
+}
+
+func (s *pauseStep) StepName() string {
+	return "pause " + s.d.String()
+}
+
 func (s *pauseStep) Retries() int {
 	return 0
 }
+
+// isPauseStep returns true if the step s is a pause step, possibly wrapped by
+// middlewares.
+func isPauseStep(s Step) bool {
+	return unwrapStep(s, func(s Step) bool {
+		_, ok := s.(*pauseStep)
+		return ok
+	})
+}
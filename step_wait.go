@@ -0,0 +1,79 @@
+package gsd
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// waitStep is a "virtual" Step implementation polling a condition function
+// until it holds, used to wait for an external event (e.g. a service to
+// become healthy) before proceeding with the next steps of the plan.
+type waitStep struct {
+	cond     func(context.Context, *State) (bool, error)
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func (s *waitStep) PreExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *waitStep) Exec(ctx context.Context, state *State) error {
+	var (
+		clock   = ContextClock(ctx)
+		timeout <-chan time.Time
+	)
+
+	if s.timeout > 0 {
+		timer := clock.NewTimer(s.timeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	for {
+		ok, err := s.cond(ctx, state)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		poll := clock.NewTimer(s.interval)
+
+		select {
+		case <-poll.C():
+
+		case <-timeout:
+			poll.Stop()
+			return ErrWaitTimeout
+
+		case <-ctx.Done():
+			poll.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *waitStep) PostExec(_ context.Context, _ *State) error {
+	return nil
+}
+
+func (s *waitStep) Cleanup(_ context.Context, _ *State) {}
+
+func (s *waitStep) DryRun(_ context.Context, _ *State) (string, error) {
+	if s.timeout > 0 {
+		return fmt.Sprintf("wait until condition holds, polling every %s for up to %s", s.interval, s.timeout), nil
+	}
+
+	return fmt.Sprintf("wait until condition holds, polling every %s", s.interval), nil
+}
+
+func (s *waitStep) StepName() string {
+	return "wait until condition"
+}
+
+func (s *waitStep) Retries() int {
+	return 0
+}
@@ -0,0 +1,187 @@
+package gsd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs are the helper functions available in the templates rendered
+// by RenderTemplate, in addition to the text/template package built-in
+// functions:
+//
+//   - default DEF VALUE: returns VALUE, or DEF if VALUE is empty
+//   - required MSG VALUE: returns VALUE, or fails with the message MSG if
+//     VALUE is empty
+//   - lower STR, upper STR, trim STR: change the case of or trim STR
+//   - replace OLD NEW STR: replaces all occurrences of OLD by NEW in STR
+//   - join SEP LIST: joins the elements of LIST (a []string or
+//     []interface{}) with SEP
+//   - quote VALUE: returns VALUE formatted as a double-quoted Go string
+//   - json VALUE: returns VALUE encoded as JSON
+var TemplateFuncs = template.FuncMap{
+	"default": func(def, v interface{}) interface{} {
+		if isEmptyValue(v) {
+			return def
+		}
+		return v
+	},
+	"required": func(msg string, v interface{}) (interface{}, error) {
+		if isEmptyValue(v) {
+			return nil, errors.New(msg)
+		}
+		return v, nil
+	},
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"join": func(sep string, list interface{}) (string, error) {
+		switch l := list.(type) {
+		case []string:
+			return strings.Join(l, sep), nil
+		case []interface{}:
+			s := make([]string, len(l))
+			for i, v := range l {
+				s[i] = fmt.Sprint(v)
+			}
+			return strings.Join(s, sep), nil
+		}
+		return "", fmt.Errorf("cannot join value of type %T", list)
+	},
+	"quote": func(v interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// isEmptyValue returns whether v is nil or the zero value of its type.
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	switch tv := v.(type) {
+	case string:
+		return tv == ""
+	case bool:
+		return !tv
+	case int:
+		return tv == 0
+	case float64:
+		return tv == 0
+	case []interface{}:
+		return len(tv) == 0
+	case []string:
+		return len(tv) == 0
+	case map[string]interface{}:
+		return len(tv) == 0
+	}
+
+	return false
+}
+
+// templateData represents the data templates are rendered with.
+type templateData struct {
+	// State are the values of the plan state with string keys.
+	State map[string]interface{}
+}
+
+// RenderTemplate renders the template text (see package text/template) with
+// the values of the state s, available as ".State" keyed by name (e.g.
+// "https://{{ .State.host }}/health"), and the helper functions
+// TemplateFuncs. Referring to a key missing from the state is an error. A
+// literal "{{" is written as {{"{{"}} in a template. The built-in steps
+// render their parameters with this function right before being executed if
+// their Templated field is set, custom steps can do the same.
+func RenderTemplate(text string, s *State) (string, error) {
+	return renderTemplate("template", text, newTemplateData(s))
+}
+
+// newTemplateData returns the template data of the state s.
+func newTemplateData(s *State) *templateData {
+	data := templateData{State: make(map[string]interface{})}
+
+	s.Range(func(k, v interface{}) bool {
+		if key, ok := k.(string); ok {
+			data.State[key] = v
+		}
+		return true
+	})
+
+	return &data
+}
+
+// renderTemplate renders the template text named name with the data.
+func renderTemplate(name, text string, data *templateData) (string, error) {
+	// Don't bother parsing text without actions.
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tpl, err := template.New(name).Option("missingkey=error").Funcs(TemplateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("unable to render %s template: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// templateRenderer renders templates with the values of a state, keeping the
+// first error encountered, allowing to render multiple step parameters at
+// once. A disabled renderer (i.e. without data) returns the texts unchanged.
+type templateRenderer struct {
+	data *templateData
+	err  error
+}
+
+// newTemplateRenderer returns a new template renderer rendering templates
+// with the values of the state s, or returning them unchanged if not enabled.
+func newTemplateRenderer(s *State, enabled bool) *templateRenderer {
+	if !enabled {
+		return &templateRenderer{}
+	}
+
+	return &templateRenderer{data: newTemplateData(s)}
+}
+
+// render returns the template text of the parameter name rendered, or an
+// empty string if an error has been encountered (see templateRenderer.err).
+func (r *templateRenderer) render(name, text string) string {
+	if r.err != nil {
+		return ""
+	}
+	if r.data == nil {
+		return text
+	}
+
+	s, err := renderTemplate(name, text, r.data)
+	if err != nil {
+		r.err = err
+	}
+
+	return s
+}
+
+// renderAll returns the templates texts of the parameter name rendered.
+func (r *templateRenderer) renderAll(name string, texts []string) []string {
+	if texts == nil {
+		return nil
+	}
+
+	rendered := make([]string, len(texts))
+	for i, text := range texts {
+		rendered[i] = r.render(name, text)
+	}
+
+	return rendered
+}
@@ -0,0 +1,76 @@
+package gsd
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	state := &State{}
+	state.Store("host", "example.net")
+	state.Store("tags", []string{"a", "b"})
+	state.Store("empty", "")
+	state.Store(42, "ignored")
+
+	tests := []struct {
+		text     string
+		expected string
+		err      bool
+	}{
+		{text: "no template", expected: "no template"},
+		{text: "https://{{ .State.host }}/health", expected: "https://example.net/health"},
+		{text: `{{ .State.empty | default "x" }}`, expected: "x"},
+		{text: `{{ .State.host | default "x" }}`, expected: "example.net"},
+		{text: `{{ .State.host | required "host is required" }}`, expected: "example.net"},
+		{text: `{{ .State.empty | required "empty is required" }}`, err: true},
+		{text: `{{ .State.host | upper }}`, expected: "EXAMPLE.NET"},
+		{text: `{{ " A " | trim | lower }}`, expected: "a"},
+		{text: `{{ .State.host | replace "." "-" }}`, expected: "example-net"},
+		{text: `{{ .State.tags | join "," }}`, expected: "a,b"},
+		{text: `{{ .State.host | quote }}`, expected: `"example.net"`},
+		{text: `{{ .State.tags | json }}`, expected: `["a","b"]`},
+		{text: "{{ .State.missing }}", err: true},
+		{text: "{{ .State.host", err: true},
+		{text: `--format {{"{{"}}.ID}}`, expected: "--format {{.ID}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			actual, err := RenderTemplate(tt.text, state)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestRenderTemplate_Step(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	// Templates are rendered with the state values at the time the step is executed.
+	plan.
+		AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error {
+				state.Store("dir", t.TempDir())
+				return nil
+			},
+		}).
+		AddStep(&WriteFileStep{Path: "{{ .State.dir }}/file", Content: []byte("test"), Templated: true}).
+		AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error {
+				data, err := os.ReadFile(state.Get("dir").(string) + "/file")
+				require.NoError(t, err)
+				require.Equal(t, "test", string(data))
+				return nil
+			},
+		})
+
+	require.NoError(t, plan.Execute(context.Background()))
+}
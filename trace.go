@@ -0,0 +1,246 @@
+package gsd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// traceEvent represents an event of the Chrome Trace Event format.
+type traceEvent struct {
+	Name     string                 `json:"name"`
+	Category string                 `json:"cat,omitempty"`
+	Phase    string                 `json:"ph"`
+	TS       float64                `json:"ts"`
+	Dur      float64                `json:"dur,omitempty"`
+	PID      int                    `json:"pid"`
+	TID      int                    `json:"tid"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+}
+
+// traceSpan represents a trace span in progress.
+type traceSpan struct {
+	name     string
+	category string
+	start    time.Time
+	track    int
+	args     map[string]interface{}
+}
+
+// traceStep tracks the spans in progress of a step execution.
+type traceStep struct {
+	name    string
+	track   int
+	step    *traceSpan
+	attempt *traceSpan
+	phase   *traceSpan
+	n       int
+}
+
+// TraceRecorder records plan execution events and exports them in the Chrome
+// Trace Event format, which can be opened in Perfetto (https://ui.perfetto.dev)
+// or chrome://tracing. It is intended to be registered on a plan using the
+// PlanOptEventHandler option:
+//
+//	tr := gsd.NewTraceRecorder()
+//	plan, err := gsd.NewPlan(gsd.PlanOptEventHandler(tr.Handle))
+//
+// The plan is rendered on its own track, and steps on separate tracks
+// allocated so that steps executing concurrently never share a track. Each
+// step span contains one span per attempt, themselves containing one span per
+// execution phase.
+type TraceRecorder struct {
+	mu     sync.Mutex
+	origin time.Time
+	plan   *traceSpan
+	steps  map[int]*traceStep
+	tracks []bool
+	events []traceEvent
+}
+
+// NewTraceRecorder returns a new trace recorder.
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{steps: make(map[int]*traceStep)}
+}
+
+// Handle records the plan execution event e.
+func (r *TraceRecorder) Handle(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.origin.IsZero() {
+		r.origin = e.Time
+	}
+
+	switch e.Type {
+	case EventPlanStart:
+		r.plan = &traceSpan{name: "plan", category: "plan", start: e.Time}
+
+	case EventPlanEnd:
+		if r.plan != nil {
+			r.end(r.plan, e.Time, e.Err)
+			r.plan = nil
+		}
+
+	case EventStepStart:
+		s := r.step(e)
+		s.step = &traceSpan{name: s.name, category: "step", start: e.Time, track: s.track}
+
+	case EventStepRetry:
+		s := r.step(e)
+		r.endAttempt(s, e.Time, e.Err)
+
+	case EventStepEnd:
+		s := r.step(e)
+		r.endAttempt(s, e.Time, e.Err)
+		if s.step != nil {
+			s.step.args = map[string]interface{}{"attempts": e.Attempt}
+			r.end(s.step, e.Time, e.Err)
+		}
+		r.release(e.Step)
+
+	case EventPhaseStart:
+		s := r.step(e)
+
+		if e.Phase != PhaseCleanup && (s.attempt == nil || s.n != e.Attempt) {
+			r.endAttempt(s, e.Time, nil)
+			s.attempt = &traceSpan{
+				name:     fmt.Sprintf("attempt %d", e.Attempt),
+				category: "attempt",
+				start:    e.Time,
+				track:    s.track,
+			}
+			s.n = e.Attempt
+		}
+
+		s.phase = &traceSpan{name: string(e.Phase), category: "phase", start: e.Time, track: s.track}
+
+	case EventPhaseEnd:
+		s := r.step(e)
+		if s.phase != nil {
+			r.end(s.phase, e.Time, e.Err)
+			s.phase = nil
+		}
+
+		if e.Phase == PhaseCleanup {
+			r.release(e.Step)
+		}
+	}
+}
+
+// step returns the tracking of the step of event e, allocating a track to it
+// if it's not being tracked yet.
+func (r *TraceRecorder) step(e Event) *traceStep {
+	if s, ok := r.steps[e.Step]; ok {
+		return s
+	}
+
+	s := traceStep{name: e.StepName, track: -1}
+	if s.name == "" {
+		s.name = fmt.Sprintf("step %d", e.Step+1)
+	}
+
+	// Track 0 is reserved to the plan, steps use the lowest free track.
+	for i := 1; i < len(r.tracks); i++ {
+		if !r.tracks[i] {
+			s.track = i
+			break
+		}
+	}
+	if s.track < 0 {
+		if len(r.tracks) == 0 {
+			r.tracks = append(r.tracks, true)
+		}
+		s.track = len(r.tracks)
+		r.tracks = append(r.tracks, false)
+	}
+	r.tracks[s.track] = true
+
+	r.steps[e.Step] = &s
+
+	return &s
+}
+
+// release stops tracking the step at index i, freeing its track.
+func (r *TraceRecorder) release(i int) {
+	if s, ok := r.steps[i]; ok {
+		r.tracks[s.track] = false
+		delete(r.steps, i)
+	}
+}
+
+// endAttempt ends the step s attempt span in progress, if any.
+func (r *TraceRecorder) endAttempt(s *traceStep, t time.Time, err error) {
+	if s.attempt != nil {
+		r.end(s.attempt, t, err)
+		s.attempt = nil
+	}
+}
+
+// end records the completed span s.
+func (r *TraceRecorder) end(s *traceSpan, t time.Time, err error) {
+	args := s.args
+	if err != nil {
+		if args == nil {
+			args = make(map[string]interface{})
+		}
+		args["error"] = err.Error()
+	}
+
+	r.events = append(r.events, traceEvent{
+		Name:     s.name,
+		Category: s.category,
+		Phase:    "X",
+		TS:       r.micros(s.start),
+		Dur:      float64(t.Sub(s.start)) / float64(time.Microsecond),
+		PID:      1,
+		TID:      s.track,
+		Args:     args,
+	})
+}
+
+// micros returns the time t in microseconds relative to the recording origin.
+func (r *TraceRecorder) micros(t time.Time) float64 {
+	return float64(t.Sub(r.origin)) / float64(time.Microsecond)
+}
+
+// WriteTrace writes the recorded execution to w in the Chrome Trace Event
+// JSON format.
+func (r *TraceRecorder) WriteTrace(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []traceEvent{{
+		Name:  "process_name",
+		Phase: "M",
+		PID:   1,
+		Args:  map[string]interface{}{"name": "gsd"},
+	}}
+
+	for i := range r.tracks {
+		name := "plan"
+		if i > 0 {
+			name = fmt.Sprintf("steps #%d", i)
+		}
+
+		events = append(events, traceEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			PID:   1,
+			TID:   i,
+			Args:  map[string]interface{}{"name": name},
+		})
+	}
+
+	events = append(events, r.events...)
+
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{
+		TraceEvents:     events,
+		DisplayTimeUnit: "ms",
+	})
+}
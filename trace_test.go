@@ -0,0 +1,94 @@
+package gsd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceRecorder(t *testing.T) {
+	tr := NewTraceRecorder()
+
+	plan, err := NewPlan(PlanOptContinueOnError(), PlanOptEventHandler(tr.Handle))
+	require.NoError(t, err)
+
+	testStep := &GenericStep{
+		Name:     "b",
+		ExecFunc: func(ctx context.Context, state *State) error { return errors.New("blah") },
+	}
+
+	require.NoError(t, plan.
+		AddStep(&GenericStep{Name: "a"}).
+		AddStep(testStep.WithRetries(1)).
+		Execute(context.Background()))
+
+	var out bytes.Buffer
+	require.NoError(t, tr.WriteTrace(&out))
+
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &trace))
+
+	type span struct {
+		Name, Category string
+		TID            int
+		Err            bool
+	}
+
+	var (
+		metadata int
+		actual   []span
+	)
+	for _, e := range trace.TraceEvents {
+		if e.Phase == "M" {
+			metadata++
+			continue
+		}
+		require.Equal(t, "X", e.Phase)
+		_, hasErr := e.Args["error"]
+		actual = append(actual, span{e.Name, e.Category, e.TID, hasErr})
+	}
+
+	require.Equal(t, 3, metadata)
+	require.Equal(t, []span{
+		{"pre-exec", "phase", 1, false},
+		{"exec", "phase", 1, false},
+		{"post-exec", "phase", 1, false},
+		{"attempt 1", "attempt", 1, false},
+		{"a", "step", 1, false},
+		{"pre-exec", "phase", 1, false},
+		{"exec", "phase", 1, true},
+		{"attempt 1", "attempt", 1, true},
+		{"pre-exec", "phase", 1, false},
+		{"exec", "phase", 1, true},
+		{"post-exec", "phase", 1, false},
+		{"attempt 2", "attempt", 1, false},
+		{"b", "step", 1, false},
+		{"cleanup", "phase", 1, false},
+		{"cleanup", "phase", 1, false},
+		{"plan", "plan", 0, false},
+	}, actual)
+}
+
+func TestTraceRecorder_ConcurrentSteps(t *testing.T) {
+	tr := NewTraceRecorder()
+
+	tr.Handle(Event{Type: EventStepStart, Step: 0})
+	tr.Handle(Event{Type: EventStepStart, Step: 1})
+	tr.Handle(Event{Type: EventStepEnd, Step: 0})
+	tr.Handle(Event{Type: EventStepStart, Step: 2})
+	tr.Handle(Event{Type: EventStepEnd, Step: 1})
+	tr.Handle(Event{Type: EventStepEnd, Step: 2})
+
+	tracks := make(map[string]int)
+	for _, e := range tr.events {
+		tracks[e.Name] = e.TID
+	}
+
+	require.Equal(t, map[string]int{"step 1": 1, "step 2": 2, "step 3": 1}, tracks)
+}
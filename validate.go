@@ -0,0 +1,117 @@
+package gsd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PlanOptValidate instructs the plan to validate itself (see Plan.Validate)
+// before executing its steps, aborting the execution if the validation fails.
+func PlanOptValidate() PlanOpt {
+	return func(p *Plan) error {
+		p.validate = true
+		return nil
+	}
+}
+
+// Validator is an optional interface a Step can implement to check its
+// configuration before the plan execution.
+type Validator interface {
+	// Validate returns a non-nil error if the step is misconfigured.
+	Validate(context.Context, *State) error
+}
+
+// ValidationError represents an error reported by a plan validation.
+type ValidationError struct {
+	// Problems are the problems detected in the plan.
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		problems[i] = p.Error()
+	}
+
+	return "plan validation failed: " + strings.Join(problems, "; ")
+}
+
+// Validate checks the plan for problems that would make its execution fail:
+// nil steps, duplicate step names, steps implementing the Validator
+// interface reporting an error, and steps implementing the DataFlow
+// interface reading State keys neither present in the plan state nor
+// written by a previous step (this check is only performed as long as all
+// previous steps declare their data flow). It returns a *ValidationError
+// listing all the problems found, or nil if the plan is valid.
+func (p *Plan) Validate(ctx context.Context) error {
+	var (
+		problems []error
+		names    = make(map[string]int)
+		written  = make(map[string]struct{})
+
+		// undeclared is set once a step not declaring the State keys it
+		// writes is encountered, since it might write any key.
+		undeclared bool
+	)
+
+	p.state.Range(func(k, _ interface{}) bool {
+		if key, ok := k.(string); ok {
+			written[key] = struct{}{}
+		}
+		return true
+	})
+
+	i := 0
+	for s := p.steps.Front(); s != nil; s, i = s.Next(), i+1 {
+		step, _ := s.Value.(Step)
+		if step == nil {
+			problems = append(problems, fmt.Errorf("step %d is nil", i+1))
+			continue
+		}
+		// Validate the steps as executed, i.e. wrapped with the plan's middlewares.
+		step = Wrap(step, p.middlewares...)
+
+		name := NameOf(step)
+		if name != "" {
+			if j, ok := names[name]; ok {
+				problems = append(problems, fmt.Errorf("steps %d and %d have the same name %q", j+1, i+1, name))
+			} else {
+				names[name] = i
+			}
+		}
+
+		var err error
+		unwrapStep(step, func(s Step) bool {
+			v, ok := s.(Validator)
+			if ok {
+				err = v.Validate(ctx, p.state)
+			}
+			return ok
+		})
+		if err != nil {
+			problems = append(problems, fmt.Errorf("step %d: %w", i+1, err))
+		}
+
+		if df := dataFlowOf(step); df == nil {
+			// Pause steps don't write to the State.
+			undeclared = undeclared || !isPauseStep(step)
+		} else {
+			for _, k := range df.Reads() {
+				if _, ok := written[k]; !ok && !undeclared {
+					problems = append(problems, fmt.Errorf("step %d reads state key %q never written before", i+1, k))
+				}
+			}
+
+			for _, k := range df.Writes() {
+				written[k] = struct{}{}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package gsd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testValidatorStep struct {
+	GenericStep
+	err error
+}
+
+func (s *testValidatorStep) Validate(_ context.Context, _ *State) error {
+	return s.err
+}
+
+func TestPlanOptValidate(t *testing.T) {
+	plan := &Plan{}
+
+	require.NoError(t, PlanOptValidate()(plan))
+	require.True(t, plan.validate)
+}
+
+func TestPlan_Validate(t *testing.T) {
+	plan, err := NewPlan()
+	require.NoError(t, err)
+
+	require.NoError(t, plan.
+		AddStep(&GenericStep{Name: "a"}).
+		AddStep(&testValidatorStep{}).
+		Validate(context.Background()))
+
+	plan, err = NewPlan()
+	require.NoError(t, err)
+
+	err = plan.
+		AddStep(&GenericStep{Name: "a"}).
+		AddStep(nil).
+		AddStep(&GenericStep{Name: "a"}).
+		AddStep(Wrap(&testValidatorStep{err: errors.New("blah")}, testMiddleware("m"))).
+		Validate(context.Background())
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Len(t, verr.Problems, 3)
+	require.EqualError(t, err, `plan validation failed: step 2 is nil; `+
+		`steps 1 and 3 have the same name "a"; step 4: blah`)
+}
+
+func TestPlan_Validate_Middlewares(t *testing.T) {
+	plan, err := NewPlan(PlanOptMiddleware(DeclareKeys([]string{"x"}, nil)))
+	require.NoError(t, err)
+
+	require.EqualError(t, plan.
+		AddStep(&GenericStep{}).
+		Validate(context.Background()),
+		`plan validation failed: step 1 reads state key "x" never written before`)
+}
+
+func TestPlan_Execute_WithValidate(t *testing.T) {
+	plan, err := NewPlan(PlanOptValidate())
+	require.NoError(t, err)
+
+	err = plan.
+		AddStep(&GenericStep{
+			ExecFunc: func(ctx context.Context, state *State) error { return testStepFunc(state, "nope") },
+		}).
+		AddStep(&testValidatorStep{err: errors.New("blah")}).
+		Execute(context.Background())
+	require.EqualError(t, err, "plan validation failed: step 2: blah")
+
+	_, ok := plan.State().Load("test")
+	require.False(t, ok)
+}